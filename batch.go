@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphgocore "github.com/microsoftgraph/msgraph-sdk-go-core"
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// batchSize is the most requests Graph accepts in a single $batch.
+const batchSize = 20
+
+// getItemsWithChildren fetches items again with their children expanded, batchSize at a time.
+// Items whose request failed within a batch are fetched on their own, so they are retried and
+// report their error the same way as any other call.
+func getItemsWithChildren(ctx context.Context, client *msgraphsdk.GraphServiceClient, items []models.DriveItemable) ([]models.DriveItemable, error) {
+	result := make([]models.DriveItemable, 0, len(items))
+	for start := 0; start < len(items); start += batchSize {
+		chunk := items[start:min(start+batchSize, len(items))]
+
+		batch := msgraphgocore.NewBatchRequest(client.GetAdapter())
+		ids := make([]string, len(chunk))
+		for i, item := range chunk {
+			request, err := itemRequest(client, item).ToGetRequestInformation(ctx, withChildren())
+			if err != nil {
+				return nil, err
+			}
+			step, err := batch.AddBatchRequestStep(*request)
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = *step.GetId()
+		}
+		response, err := withRetry(ctx, graphRetry, func() (msgraphgocore.BatchResponse, error) {
+			return batch.Send(ctx, client.GetAdapter())
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, item := range chunk {
+			if step := response.GetResponseById(ids[i]); step != nil && step.GetStatus() != nil && *step.GetStatus() < 300 {
+				fetched, err := msgraphgocore.GetBatchResponseById[models.DriveItemable](response, ids[i], models.CreateDriveItemFromDiscriminatorValue)
+				if err == nil {
+					result = append(result, fetched)
+					continue
+				}
+			}
+			fetched, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+				return itemRequest(client, item).Get(ctx, withChildren())
+			})
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, fetched)
+		}
+	}
+	return result, nil
+}
+
+// withChildren selects the drive item fields with the children expanded. It is built for every
+// call, since selectItemProperties changes the fields after the package is initialized.
+func withChildren() *drives2.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration {
+	return &drives2.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &drives2.ItemItemsDriveItemItemRequestBuilderGetQueryParameters{
+			Select: driveItemFields,
+			Expand: []string{expandChildren},
+		},
+	}
+}
+
+func itemRequest(client *msgraphsdk.GraphServiceClient, item models.DriveItemable) *drives2.ItemItemsDriveItemItemRequestBuilder {
+	return client.Drives().ByDriveId(*item.GetParentReference().GetDriveId()).Items().ByDriveItemId(*item.GetId())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// bootstrap sets up a fresh data directory from the environment, so the tool can be used on its
+// own without anything writing externalLinks.json first. It only acts when ONEDRIVE_LINKS is set
+// and metadata.json does not exist yet, and never overwrites existing files, so a first run that
+// fails can simply be repeated:
+//
+//   - ONEDRIVE_LINKS lists the shared links to sync, separated by commas or newlines.
+//   - ONEDRIVE_OUTPUT_DIR sets outputDir.
+//
+// Every file found is synced, since there is nobody to select files yet.
+func bootstrap(dataPath string) error {
+	links := strings.FieldsFunc(os.Getenv("ONEDRIVE_LINKS"), func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	if len(links) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(path.Join(dataPath, "metadata.json")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return err
+	}
+
+	externalLinkPath := path.Join(dataPath, "externalLinks.json")
+	if _, err := os.Stat(externalLinkPath); os.IsNotExist(err) {
+		externalLinks := map[string]string{}
+		for _, link := range links {
+			if link = strings.TrimSpace(link); link != "" {
+				externalLinks[link] = ""
+			}
+		}
+		if err := writeJSON(externalLinkPath, externalLinks); err != nil {
+			return err
+		}
+		logrus.Info("Created " + externalLinkPath)
+	}
+
+	configPath := path.Join(dataPath, "config.json")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		config := Config{
+			OutputDir:    os.Getenv("ONEDRIVE_OUTPUT_DIR"),
+			SyncNewFiles: true,
+		}
+		if err := writeJSON(configPath, config); err != nil {
+			return err
+		}
+		logrus.Info("Created " + configPath)
+	}
+	return nil
+}
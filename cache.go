@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// cacheKey identifies the current content of item in the download cache, or is empty if the
+// content has no hash or cTag to tell versions apart.
+func cacheKey(item models.DriveItemable) string {
+	version := remoteHash(item)
+	if version == "" {
+		version = deref(item.GetCTag())
+	}
+	if version == "" || item.GetParentReference() == nil || item.GetParentReference().GetDriveId() == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(*item.GetParentReference().GetDriveId() + "/" + *item.GetId() + "/" + version))
+	return hex.EncodeToString(sum[:])
+}
+
+// copyFromCache writes the content of item to dst from the download cache and reports whether it
+// was found there.
+func (s *Syncer) copyFromCache(item models.DriveItemable, dst string) (bool, error) {
+	key := cacheKey(item)
+	if s.cacheDir == "" || key == "" {
+		return false, nil
+	}
+	src := filepath.Join(s.cacheDir, key)
+	if _, err := os.Stat(src); err != nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".cached")
+	defer os.Remove(tmp)
+	if err := copyFile(src, tmp); err != nil {
+		return false, err
+	}
+	return true, s.moveIntoPlace(tmp, dst)
+}
+
+// addToCache copies the downloaded content of item at src to the download cache. Failures only
+// cost a download in another workspace and are logged.
+func (s *Syncer) addToCache(item models.DriveItemable, src string) {
+	key := cacheKey(item)
+	if s.cacheDir == "" || key == "" {
+		return
+	}
+	dst := filepath.Join(s.cacheDir, key)
+	if _, err := os.Stat(dst); err == nil {
+		return
+	}
+	// Runs sharing the cache may add the same file at the same time, so it is copied under a
+	// name of its own and renamed into place.
+	f, err := os.CreateTemp(s.cacheDir, key+".partial-*")
+	if err == nil {
+		f.Close()
+		defer os.Remove(f.Name())
+		if err = copyFile(src, f.Name()); err == nil {
+			err = os.Rename(f.Name(), dst)
+		}
+	}
+	if err != nil {
+		logrus.Warn(fmt.Sprintf("Could not add %s to the download cache: %v", *item.GetName(), err))
+	}
+}
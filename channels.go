@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ChannelSource is a Teams channel whose files are synced.
+type ChannelSource struct {
+	TeamID    string `json:"teamId"`
+	ChannelID string `json:"channelId"`
+}
+
+// getItemsForChannel returns the files of a Teams channel, with the webUrl of its files folder
+// to attribute them to. The files live in the SharePoint site of the team, or of the channel for
+// private and shared channels.
+func getItemsForChannel(ctx context.Context, client *msgraphsdk.GraphServiceClient, source ChannelSource) (string, []models.DriveItemable, error) {
+	folder, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return client.Teams().ByTeamId(source.TeamID).Channels().ByChannelId(source.ChannelID).FilesFolder().Get(ctx, nil)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if folder.GetParentReference() == nil || folder.GetParentReference().GetDriveId() == nil {
+		return "", nil, fmt.Errorf("no files folder for channel %s of team %s", source.ChannelID, source.TeamID)
+	}
+	children, err := getItemsForRemoteItem(ctx, client, *folder.GetParentReference().GetDriveId(), *folder.GetId())
+	if err != nil {
+		return "", nil, err
+	}
+	logrus.Info(fmt.Sprintf("Found %d files in channel %s", len(children), deref(folder.GetName())))
+	return deref(folder.GetWebUrl()), children, nil
+}
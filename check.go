@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// exitOutOfDate is the exit status of --check when the workspace is out of date, to tell it apart
+// from a failed check.
+const exitOutOfDate = 2
+
+// isCheckMode reports whether the binary was started with --check, to only verify that the
+// workspace is up to date without syncing.
+func isCheckMode() bool {
+	return len(os.Args) > 1 && os.Args[1] == "--check"
+}
+
+// outOfDate lists what a sync of items would change in the workspace: synced files that are
+// missing, changed or no longer found, and new files if they are synced automatically.
+func outOfDate(items map[string]models.DriveItemable, metadata map[string]FileDetails, incomplete map[string]bool, config Config, outputDir string) []string {
+	var result []string
+	for id, item := range items {
+		detail, ok := metadata[id]
+		if !ok {
+			if config.SyncNewFiles {
+				result = append(result, "new: "+getDisplayName(item))
+			}
+			continue
+		}
+		if !detail.Sync {
+			continue
+		}
+		filePath := path.Join(config.routeDir(item), id, *item.GetName())
+		if _, err := os.Stat(path.Join(outputDir, filePath)); err != nil {
+			result = append(result, "missing: "+getDisplayName(item))
+		} else if !isUpToDate(item, detail) || config.forcesResync(item) {
+			result = append(result, "changed: "+getDisplayName(item))
+		}
+	}
+	for id, detail := range metadata {
+		if _, ok := items[id]; !ok && detail.Sync && !fromIncompleteSource(detail, incomplete) {
+			result = append(result, "removed: "+detail.DisplayName)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// exitWithCheck prints what is out of date and exits with exitOutOfDate if anything is.
+func exitWithCheck(items map[string]models.DriveItemable, metadata map[string]FileDetails, incomplete map[string]bool, config Config, outputDir string) {
+	drift := outOfDate(items, metadata, incomplete, config, outputDir)
+	for _, line := range drift {
+		fmt.Println(line)
+	}
+	if len(drift) > 0 {
+		os.Exit(exitOutOfDate)
+	}
+	os.Exit(0)
+}
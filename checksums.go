@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Checksum algorithms of the manifests written next to the synced files.
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumCRC32C = "crc32c"
+	ChecksumXXHash = "xxhash"
+)
+
+// manifestNames are the files in the output directory each manifest is written to.
+var manifestNames = map[string]string{
+	ChecksumSHA256: "SHA256SUMS",
+	ChecksumCRC32C: "CRC32CSUMS",
+	ChecksumXXHash: "XXH64SUMS",
+}
+
+func newChecksum(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumXXHash:
+		return xxhash.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// writeManifests writes a manifest of the downloaded files in outputDir for each algorithm, in the
+// format of sha256sum and its siblings: the hex encoded checksum and the path relative to
+// outputDir on each line. Files are read once for all algorithms.
+func writeManifests(outputDir string, metadata map[string]FileDetails, algorithms []string) error {
+	if len(algorithms) == 0 {
+		return nil
+	}
+
+	var paths []string
+	for id, detail := range metadata {
+		if detail.Sync {
+			paths = append(paths, detail.localPath(id))
+		}
+	}
+	slices.Sort(paths)
+
+	lines := make([]strings.Builder, len(algorithms))
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for _, p := range paths {
+		for i, algorithm := range algorithms {
+			hashes[i] = newChecksum(algorithm)
+			writers[i] = hashes[i]
+		}
+		f, err := os.Open(filepath.Join(outputDir, filepath.FromSlash(p)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.MultiWriter(writers...), f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %w", p, err)
+		}
+		for i := range algorithms {
+			fmt.Fprintf(&lines[i], "%s  %s\n", hex.EncodeToString(hashes[i].Sum(nil)), p)
+		}
+	}
+
+	for i, algorithm := range algorithms {
+		if err := replaceFile(filepath.Join(outputDir, manifestNames[algorithm]), []byte(lines[i].String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultChunkSize = 10 << 20
+	downloadURLKey   = "@microsoft.graph.downloadUrl"
+)
+
+// downloadChunked downloads item in ranges of chunkSize into a .partial file and moves it to
+// downloadPath once complete and verified, so only one chunk at a time is held in memory. A .partial
+// file left behind by an interrupted run is resumed as long as the item has not been modified since.
+func (s *Syncer) downloadChunked(ctx context.Context, item models.DriveItemable, downloadPath string) error {
+	// Getting the download URL brings item up to date first, so the size, modification time and
+	// hash below are those of the version being downloaded.
+	downloadURL, err := s.downloadURL(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	partialPath := s.partialPath(item, downloadPath)
+	modified := *item.GetLastModifiedDateTime()
+	size := itemSize(item)
+
+	// The modification time of the .partial file is set to that of the item, so a partial download
+	// of an older version is never resumed.
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil && info.ModTime().Equal(modified) && info.Size() <= size {
+		offset = info.Size()
+		logrus.Info(fmt.Sprintf("Resuming download of %s at %s", *item.GetName(), formatBytes(offset)))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(partialPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	// The content is hashed as it is written, starting with what an earlier run downloaded.
+	var hash quickXorHasher
+	if _, err := io.CopyN(&hash, f, offset); err != nil {
+		return err
+	}
+
+	for offset < size {
+		end := min(offset+s.config.chunkSize(), size) - 1
+		chunk, err := withRetry(ctx, contentRetry, func() ([]byte, error) {
+			return getRange(ctx, downloadURL, offset, end)
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.MultiWriter(f, &hash).Write(chunk); err != nil {
+			return err
+		}
+		offset += int64(len(chunk))
+		s.progress.transferred(int64(len(chunk)))
+		if err := os.Chtimes(partialPath, time.Now(), modified); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// A corrupted download must not be resumed, so the .partial file is removed either way.
+	if err := verifyHashString(item, hash.String()); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+	if err := s.moveIntoPlace(partialPath, downloadPath); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+	return nil
+}
+
+// partialsDir is the subdirectory of the temp directory holding chunked downloads, apart from
+// the files of other programs should the temp directory be shared, such as /tmp.
+const partialsDir = "onedrive-partials"
+
+// partialPath returns where the chunked download of item to downloadPath is kept until it is
+// complete: in partialsDir of the temp directory if one is configured, next to the file otherwise.
+func (s *Syncer) partialPath(item models.DriveItemable, downloadPath string) string {
+	if s.config.TempDir != "" {
+		return filepath.Join(s.config.TempDir, partialsDir, *item.GetId()+".partial")
+	}
+	return downloadPath + ".partial"
+}
+
+// downloadURL returns the short-lived pre-authenticated URL of the content of item. The item is
+// fetched again for it and reconciled, should it have changed since it was listed.
+func (s *Syncer) downloadURL(ctx context.Context, item models.DriveItemable) (string, error) {
+	if err := s.reconcile(ctx, item); err != nil {
+		return "", err
+	}
+	if u, ok := item.GetAdditionalData()[downloadURLKey].(*string); ok && u != nil {
+		return *u, nil
+	}
+	return "", fmt.Errorf("no download url for %s", *item.GetName())
+}
+
+// getRange downloads the bytes from start to end inclusive.
+func getRange(ctx context.Context, downloadURL string, start, end int64) ([]byte, error) {
+	return getContent(ctx, downloadURL, fmt.Sprintf("bytes=%d-%d", start, end))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	az "github.com/microsoft/kiota-authentication-azure-go"
+	khttp "github.com/microsoft/kiota-http-go"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphgocore "github.com/microsoftgraph/msgraph-sdk-go-core"
+)
+
+// newGraphClient returns a Graph client for the Graph API of cloud authenticating with cred whose
+// requests go through transport, with the SDK's default middleware for redirects and compression.
+// The SDK's retry handler is left out: withRetry retries every call according to graphRetry, and
+// retrying in both places would multiply the attempts and ignore graphRetry.maxRetries of 0.
+func newGraphClient(cred azcore.TokenCredential, transport http.RoundTripper, config TransportConfig, cloud Cloud) (*msgraphsdk.GraphServiceClient, error) {
+	auth, err := az.NewAzureIdentityAuthenticationProviderWithScopesAndValidHosts(cred, []string{cloud.scope()}, []string{cloud.GraphHost})
+	if err != nil {
+		return nil, err
+	}
+
+	options := msgraphsdk.GetDefaultClientOptions()
+	middlewares := slices.DeleteFunc(msgraphgocore.GetDefaultMiddlewaresWithOptions(&options), func(m khttp.Middleware) bool {
+		_, ok := m.(*khttp.RetryHandler)
+		return ok
+	})
+	httpClient := &http.Client{
+		Transport: khttp.NewCustomTransportWithParentTransport(transport, middlewares...),
+		// Redirects are followed by the redirect middleware.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: config.RequestTimeout,
+	}
+	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(auth, nil, nil, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	adapter.SetBaseUrl(cloud.graphURL())
+	return msgraphsdk.NewGraphServiceClient(adapter), nil
+}
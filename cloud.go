@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+const (
+	// CloudGlobal is the worldwide Microsoft 365 service. This is the default.
+	CloudGlobal = "global"
+	// CloudUSGov is Microsoft 365 GCC High.
+	CloudUSGov = "usgov"
+	// CloudUSGovDoD is Microsoft 365 DoD.
+	CloudUSGovDoD = "usgov-dod"
+	// CloudChina is Microsoft 365 operated by 21Vianet.
+	CloudChina = "china"
+)
+
+// Cloud holds the endpoints of a national cloud.
+type Cloud struct {
+	// GraphHost is the host name of the Graph API.
+	GraphHost string
+	// Authority is the Microsoft Entra ID endpoint tokens are requested from.
+	Authority cloud.Configuration
+}
+
+// graphURL returns the base URL of the v1.0 Graph API.
+func (c Cloud) graphURL() string {
+	return "https://" + c.GraphHost + "/v1.0"
+}
+
+// scope returns the scope of tokens for the Graph API.
+func (c Cloud) scope() string {
+	return "https://" + c.GraphHost + "/.default"
+}
+
+// tokenURL returns the OAuth token endpoint of tenantID.
+func (c Cloud) tokenURL(tenantID string) string {
+	return strings.TrimSuffix(c.Authority.ActiveDirectoryAuthorityHost, "/") + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token"
+}
+
+// cloudByName returns the national cloud with the given name, as set with ONEDRIVE_CLOUD. Tenants
+// outside the global service only accept tokens issued for their own cloud, so the bearer token,
+// if used, must be issued for it as well.
+func cloudByName(name string) (Cloud, error) {
+	switch name {
+	case "", CloudGlobal:
+		return Cloud{GraphHost: "graph.microsoft.com", Authority: cloud.AzurePublic}, nil
+	case CloudUSGov:
+		return Cloud{GraphHost: "graph.microsoft.us", Authority: cloud.AzureGovernment}, nil
+	case CloudUSGovDoD:
+		return Cloud{GraphHost: "dod-graph.microsoft.us", Authority: cloud.AzureGovernment}, nil
+	case CloudChina:
+		return Cloud{GraphHost: "microsoftgraph.chinacloudapi.cn", Authority: cloud.AzureChina}, nil
+	default:
+		return Cloud{}, fmt.Errorf("invalid cloud %q, must be one of %q, %q, %q or %q", name, CloudGlobal, CloudUSGov, CloudUSGovDoD, CloudChina)
+	}
+}
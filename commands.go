@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+)
+
+// runCommand runs a command given on the command line instead of a sync.
+func runCommand(ctx context.Context, client *msgraphsdk.GraphServiceClient, command string) error {
+	switch command {
+	case "discover":
+		return discover(ctx, client)
+	case "doctor":
+		return doctor(ctx, client)
+	case "workspaces":
+		if len(os.Args) < 3 {
+			return fmt.Errorf("usage: %s workspaces <batch.json>", os.Args[0])
+		}
+		return syncWorkspaces(ctx, os.Args[2])
+	case "migrate":
+		if len(os.Args) < 3 {
+			return fmt.Errorf("usage: %s migrate <output-dir>", os.Args[0])
+		}
+		return migrate(os.Args[2])
+	case "gc":
+		return gc()
+	case "export-state":
+		var p string
+		if len(os.Args) > 2 {
+			p = os.Args[2]
+		}
+		return exportState(p)
+	case "import-state":
+		if len(os.Args) < 3 {
+			return fmt.Errorf("usage: %s import-state <state.json>", os.Args[0])
+		}
+		return importState(os.Args[2])
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
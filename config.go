@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Config holds the optional sync settings read from config.json in the data directory.
+type Config struct {
+	// Sites lists SharePoint sites whose document libraries are synced in addition to the external links.
+	Sites []SiteSource `json:"sites,omitempty"`
+	// Drives are folders and files addressed by drive ID and path, without a shared link.
+	Drives []DriveSource `json:"drives,omitempty"`
+	// Lists are SharePoint lists whose rows link to documents to sync.
+	Lists []ListSource `json:"lists,omitempty"`
+	// LinkProfiles maps shared links to the profile, in the file named by ONEDRIVE_PROFILES, to
+	// resolve and download them with, for content shared from other accounts or tenants. Other
+	// links and sources use the credentials of the run.
+	LinkProfiles map[string]string `json:"linkProfiles,omitempty"`
+	// LinkPaths limits shared links of folders to the subfolder at the given path below the shared
+	// folder, e.g. "Reports/2024".
+	LinkPaths map[string]string `json:"linkPaths,omitempty"`
+	// MyDrive syncs the whole OneDrive of the signed-in user.
+	MyDrive bool `json:"myDrive,omitempty"`
+	// Search syncs the files in the OneDrive of the signed-in user, and shared with them, that match
+	// this search query, e.g. "contract". Matching folders are synced with their content.
+	Search string `json:"search,omitempty"`
+	// SharedWithMe syncs every file and folder shared with the signed-in user.
+	SharedWithMe bool `json:"sharedWithMe,omitempty"`
+	// SharedWithMeFilter limits SharedWithMe to items whose name contains it, ignoring case.
+	SharedWithMeFilter string `json:"sharedWithMeFilter,omitempty"`
+	// Groups lists Microsoft 365 groups, by ID or display name, whose drives are synced. This
+	// covers the files of Teams.
+	Groups []string `json:"groups,omitempty"`
+	// Channels lists Teams channels whose files are synced.
+	Channels []ChannelSource `json:"channels,omitempty"`
+	// AllGroupDrives syncs the drives of every Microsoft 365 group the user is a member of.
+	AllGroupDrives bool `json:"allGroupDrives,omitempty"`
+	// GroupFilter limits AllGroupDrives to groups whose display name contains it, ignoring case.
+	GroupFilter string `json:"groupFilter,omitempty"`
+	// OutputDir is where synced files are written. Relative paths are resolved against the workspace.
+	// It defaults to the integration's data directory.
+	OutputDir string `json:"outputDir,omitempty"`
+	// SnapshotDir enables snapshots: after every complete run the synced files are recorded in a new
+	// directory below it named after the start of the run, e.g. 20240131T080000Z, for point-in-time
+	// views of the corpus. Unchanged files are hard linked, so they take no extra space when
+	// SnapshotDir is on the same filesystem as OutputDir. Snapshots must not be modified.
+	SnapshotDir string `json:"snapshotDir,omitempty"`
+	// SnapshotRetention limits the snapshots kept by the gc command.
+	SnapshotRetention *SnapshotRetention `json:"snapshotRetention,omitempty"`
+	// AllowExternalOutput must be set for an OutputDir or SnapshotDir outside the workspace.
+	AllowExternalOutput bool `json:"allowExternalOutput,omitempty"`
+	// DetectLanguage records the language of downloaded text files in the metadata.
+	DetectLanguage bool `json:"detectLanguage,omitempty"`
+	// OCRCommand is run for downloaded images and PDFs, with {file} replaced by the path of the file.
+	// Its standard output is stored next to the file as <name>.txt, e.g. ["tesseract", "{file}", "stdout"].
+	OCRCommand []string `json:"ocrCommand,omitempty"`
+	// ConvertHTML stores a Markdown version of downloaded .html and .aspx pages as <name>.md.
+	ConvertHTML bool `json:"convertHTML,omitempty"`
+	// TranscribeCommand is run for downloaded audio and video files like OCRCommand. Its output is
+	// stored as <name>.transcript.txt.
+	TranscribeCommand []string `json:"transcribeCommand,omitempty"`
+	// MaxDepth limits how many levels of subfolders below a shared folder, site library or drive are
+	// synced, to leave out deep archive and backup trees. 1 syncs the files of the folder itself and
+	// its direct subfolders. Zero means no limit.
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// Extensions limits the sync to files with these extensions, e.g. ["pdf", "docx", "md"], compared
+	// ignoring case and with or without the leading dot.
+	Extensions []string `json:"extensions,omitempty"`
+	// ExcludeExtensions leaves out files with these extensions, e.g. ["mp4", "zip"].
+	ExcludeExtensions []string `json:"excludeExtensions,omitempty"`
+	// IncludePatterns limits the sync to files whose path from the root of their drive matches one of
+	// these globs, e.g. "**/*.pdf". A ** segment matches any number of folders, patterns without a
+	// leading / match at any depth, and a leading ! turns a pattern into an exception to the ones
+	// before it. The last matching pattern wins.
+	IncludePatterns []string `json:"includePatterns,omitempty"`
+	// ExcludePatterns leaves out files matching these globs, e.g. "archive/**", with the same syntax.
+	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+	// SkipEmptyFiles leaves zero-byte files, often placeholders, out of the sync.
+	SkipEmptyFiles bool `json:"skipEmptyFiles,omitempty"`
+	// MinFileSize is the size in bytes below which files are skipped, to drop stubs such as desktop.ini.
+	MinFileSize int64 `json:"minFileSize,omitempty"`
+	// MaxFileSize is the size in bytes above which files, such as videos and datasets, are skipped
+	// and reported instead of downloaded. Zero means no limit.
+	MaxFileSize int64 `json:"maxFileSize,omitempty"`
+	// IncludeHidden syncs system files, Office lock files and dotfiles, which are skipped by default.
+	IncludeHidden bool `json:"includeHidden,omitempty"`
+	// TempDir holds files while they are being written. It defaults to the directory of each file,
+	// which keeps the final rename cheap; a scratch volume can be used instead.
+	TempDir string `json:"tempDir,omitempty"`
+	// Fsync is one of "none" (default), "file" or "end" and trades durability of written files against throughput.
+	Fsync string `json:"fsync,omitempty"`
+	// Concurrency is the number of files downloaded, and shared links resolved, in parallel. It
+	// defaults to defaultConcurrency.
+	Concurrency int `json:"concurrency,omitempty"`
+	// ChunkedDownloadThreshold is the size in bytes from which files are downloaded in ranges of
+	// ChunkSize, so an interrupted download resumes instead of starting over. Zero disables it.
+	ChunkedDownloadThreshold int64 `json:"chunkedDownloadThreshold,omitempty"`
+	// ChunkSize is the size in bytes of each range. It defaults to defaultChunkSize.
+	ChunkSize int64 `json:"chunkSize,omitempty"`
+	// MetadataFormat is one of "camelCase" (default), "snake_case" or "legacy" and controls the keys
+	// written to metadata.json for existing consumers. The legacy layout only keeps the original
+	// fields, so files renamed or converted while it is used may leave stale copies behind.
+	MetadataFormat string `json:"metadataFormat,omitempty"`
+	// TimestampFormat is one of "rfc3339" (default), which records times in UTC, or "epochMillis".
+	TimestampFormat string `json:"timestampFormat,omitempty"`
+	// IncludeListItemFields records the content type and column values of files in SharePoint
+	// document libraries, which often carry classification used to filter retrieval.
+	IncludeListItemFields bool `json:"includeListItemFields,omitempty"`
+	// ItemProperties are additional drive item properties, such as "createdDateTime" or "shared",
+	// fetched and recorded as they are in the metadata for downstream systems.
+	ItemProperties []string `json:"itemProperties,omitempty"`
+	// GraphRetry and ContentRetry override how Graph API calls and file downloads are retried
+	// after throttling and other transient errors.
+	GraphRetry   *RetryConfig `json:"graphRetry,omitempty"`
+	ContentRetry *RetryConfig `json:"contentRetry,omitempty"`
+	// Routes sort downloaded files into subdirectories by content type, e.g. pdfs/ and images/.
+	Routes []Route `json:"routes,omitempty"`
+	// ModifiedAfter limits the sync to files last modified after this time, either RFC 3339, e.g.
+	// "2024-01-31T08:00:00Z", or a date, e.g. "2024-01-31", which is taken as midnight UTC.
+	ModifiedAfter string `json:"modifiedAfter,omitempty"`
+	// Authors limits the sync to files created or last modified by users with these email addresses
+	// or UPNs, compared ignoring case.
+	Authors []string `json:"authors,omitempty"`
+	// SyncNewFiles downloads files that are not in the metadata yet, instead of waiting for them to
+	// be selected for sync.
+	SyncNewFiles bool `json:"syncNewFiles,omitempty"`
+	// Checksums lists the algorithms, "sha256", "crc32c" or "xxhash", of manifests of the synced files
+	// written to the output directory after every run, e.g. SHA256SUMS, for verifying a copy of the
+	// corpus. CRC32C and xxHash are much faster to verify on large corpora.
+	Checksums []string `json:"checksums,omitempty"`
+	// DeletionLog appends the files deleted from the output directory during each run to
+	// deletions.ndjson in the data directory, so downstream indexes can drop them promptly.
+	DeletionLog bool `json:"deletionLog,omitempty"`
+	// MarkdownReport also writes a summary of every run to SYNC_REPORT.md in the data directory.
+	MarkdownReport bool `json:"markdownReport,omitempty"`
+	// Sample downloads only a sample of the files, to preview the sync of a large library.
+	Sample *SampleConfig `json:"sample,omitempty"`
+	// Resync lists synced files to download again even if they are up to date, by item ID or by
+	// path from the root of their drive, e.g. /Projects/Docs/plan.docx. A folder path covers every
+	// file below it. It is meant for the arguments of a single run rather than config.json.
+	Resync []string `json:"resync,omitempty"`
+	// RunRetryDelaySeconds makes a run that fails partway through on rejected credentials or a lost
+	// network connection wait this long, for example for a token to be renewed, and then retry once
+	// with the files not synced yet. Zero fails the run right away.
+	RunRetryDelaySeconds int `json:"runRetryDelaySeconds,omitempty"`
+	// Shortcuts is one of "keep" (default), "skip" or "resolve" and controls how .url shortcuts to
+	// documents are synced.
+	Shortcuts string `json:"shortcuts,omitempty"`
+	// MaxMemoryMB keeps memory use below this many megabytes by holding back downloads while large
+	// files are being processed, to avoid being killed in constrained containers. Close to the limit
+	// the metadata is saved and freed memory returned to the system. Listing is not paused and the
+	// listed items and metadata are not limited, since the sync needs all of them. Zero means no limit.
+	MaxMemoryMB int64 `json:"maxMemoryMB,omitempty"`
+}
+
+const defaultConcurrency = 4
+
+func (c Config) concurrency() int {
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return defaultConcurrency
+}
+
+func (c Config) chunkSize() int64 {
+	if c.ChunkSize > 0 {
+		return c.ChunkSize
+	}
+	return defaultChunkSize
+}
+
+// validate reports settings that have no valid meaning.
+func (c Config) validate() error {
+	switch c.Fsync {
+	case "", FsyncNone, FsyncFile, FsyncEnd:
+	default:
+		return fmt.Errorf("invalid fsync %q, must be one of %q, %q or %q", c.Fsync, FsyncNone, FsyncFile, FsyncEnd)
+	}
+	switch c.MetadataFormat {
+	case "", MetadataCamelCase, MetadataSnakeCase, MetadataLegacy:
+	default:
+		return fmt.Errorf("invalid metadataFormat %q, must be one of %q, %q or %q", c.MetadataFormat, MetadataCamelCase, MetadataSnakeCase, MetadataLegacy)
+	}
+	switch c.Shortcuts {
+	case "", ShortcutsKeep, ShortcutsSkip, ShortcutsResolve:
+	default:
+		return fmt.Errorf("invalid shortcuts %q, must be one of %q, %q or %q", c.Shortcuts, ShortcutsKeep, ShortcutsSkip, ShortcutsResolve)
+	}
+	switch c.TimestampFormat {
+	case "", TimestampRFC3339, TimestampEpochMillis:
+	default:
+		return fmt.Errorf("invalid timestampFormat %q, must be %q or %q", c.TimestampFormat, TimestampRFC3339, TimestampEpochMillis)
+	}
+	if _, err := c.modifiedAfter(); err != nil {
+		return err
+	}
+	for _, patterns := range [][]string{c.IncludePatterns, c.ExcludePatterns} {
+		if err := validatePatterns(patterns); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	for _, algorithm := range c.Checksums {
+		if _, ok := manifestNames[algorithm]; !ok {
+			return fmt.Errorf("invalid checksums entry %q, must be one of %q, %q or %q", algorithm, ChecksumSHA256, ChecksumCRC32C, ChecksumXXHash)
+		}
+	}
+	for _, site := range c.Sites {
+		if site.URL == "" && site.ID == "" {
+			return fmt.Errorf("sites entry has neither url nor id")
+		}
+	}
+	for _, channel := range c.Channels {
+		if channel.TeamID == "" || channel.ChannelID == "" {
+			return fmt.Errorf("channels entry needs both teamId and channelId")
+		}
+	}
+	for _, list := range c.Lists {
+		if list.SiteURL == "" || list.List == "" || list.Column == "" {
+			return fmt.Errorf("lists entry %q needs siteUrl, list and column", list.List)
+		}
+	}
+	for _, drive := range c.Drives {
+		if drive.DriveID == "" {
+			return fmt.Errorf("drives entry for path %q has no driveId", drive.Path)
+		}
+	}
+	for _, route := range c.Routes {
+		if err := route.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifiedAfter returns the parsed ModifiedAfter, or the zero time if it is not set.
+func (c Config) modifiedAfter() (time.Time, error) {
+	if c.ModifiedAfter == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, c.ModifiedAfter); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, c.ModifiedAfter)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid modifiedAfter %q, must be a date or an RFC 3339 time", c.ModifiedAfter)
+	}
+	return t, nil
+}
+
+// outputPath returns the absolute directory synced files are written to.
+func (c Config) outputPath(workspaceDir, dataPath string) (string, error) {
+	if c.OutputDir == "" {
+		return filepath.Abs(dataPath)
+	}
+
+	workspace, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return "", err
+	}
+	dir := c.OutputDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workspace, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	if dir == filepath.Dir(dir) {
+		return "", fmt.Errorf("outputDir %s must not be the filesystem root", c.OutputDir)
+	}
+	if dir == workspace {
+		return "", fmt.Errorf("outputDir %s must not be the workspace itself", c.OutputDir)
+	}
+	if !c.AllowExternalOutput && !isInside(workspace, dir) {
+		return "", fmt.Errorf("outputDir %s is outside the workspace %s, set allowExternalOutput to use it", c.OutputDir, workspace)
+	}
+	return dir, nil
+}
+
+type SiteSource struct {
+	// URL of the site, e.g. https://contoso.sharepoint.com/sites/hr
+	URL string `json:"url,omitempty"`
+	// ID of the site as listed by the discover command, which can be given instead of URL and
+	// keeps working when the site is renamed.
+	ID string `json:"id,omitempty"`
+	// Libraries limits the sync to document libraries with these names, e.g. ["Policies", "Procedures"].
+	// Empty means every library on the site.
+	Libraries []string `json:"libraries,omitempty"`
+	// IncludeSubsites also syncs the libraries of the site's subsites.
+	IncludeSubsites bool `json:"includeSubsites,omitempty"`
+	// MaxSubsiteDepth limits how many levels of subsites are visited. Zero means no limit.
+	MaxSubsiteDepth int `json:"maxSubsiteDepth,omitempty"`
+}
+
+// source identifies the site in the sources of the files found through it.
+func (s SiteSource) source() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return "sites/" + s.ID
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+const (
+	// AuthToken uses the bearer token in GPTSCRIPT_GRAPH_MICROSOFT_COM_BEARER_TOKEN. This is the default.
+	AuthToken = "token"
+	// AuthDeviceCode signs in interactively with a code entered at https://microsoft.com/devicelogin.
+	AuthDeviceCode = "device-code"
+	// AuthClientSecret signs in as the app itself with ONEDRIVE_CLIENT_SECRET, for unattended
+	// deployments. Sources tied to a signed-in user, such as allGroupDrives, are not available.
+	AuthClientSecret = "client-secret"
+	// AuthRefreshToken redeems ONEDRIVE_REFRESH_TOKEN for access tokens and renews them during the sync.
+	AuthRefreshToken = "refresh-token"
+	// AuthDefault tries the credentials of the environment in turn: the AZURE_* variables, workload
+	// and managed identity, then the Azure CLI and Azure Developer CLI. This suits unattended runs in Azure.
+	AuthDefault = "default"
+)
+
+// newCredential returns the credential selected with profile.Auth. The tenant and app
+// registration of the profile are signed in to where that applies. Sign-in requests are sent with
+// httpClient to the authority of cloud, so they use the same proxy as everything else.
+func newCredential(token string, httpClient *http.Client, profile Profile, cloud Cloud) (azcore.TokenCredential, error) {
+	options := azcore.ClientOptions{Transport: httpClient, Cloud: cloud.Authority}
+	switch auth := profile.Auth; auth {
+	case "", AuthToken:
+		return NewStaticTokenCredential(token), nil
+	case AuthDeviceCode:
+		return azidentity.NewDeviceCodeCredential(&azidentity.DeviceCodeCredentialOptions{
+			ClientOptions: options,
+			TenantID:      profile.TenantID,
+			ClientID:      profile.ClientID,
+			// Stdout is reserved for command output, so the prompt goes to stderr.
+			UserPrompt: func(_ context.Context, message azidentity.DeviceCodeMessage) error {
+				_, err := fmt.Fprintln(os.Stderr, message.Message)
+				return err
+			},
+		})
+	case AuthClientSecret:
+		if profile.TenantID == "" || profile.ClientID == "" || profile.ClientSecret == "" {
+			return nil, fmt.Errorf("auth %s requires ONEDRIVE_TENANT_ID, ONEDRIVE_CLIENT_ID and ONEDRIVE_CLIENT_SECRET or their profile settings", auth)
+		}
+		return azidentity.NewClientSecretCredential(profile.TenantID, profile.ClientID, profile.ClientSecret, &azidentity.ClientSecretCredentialOptions{
+			ClientOptions: options,
+		})
+	case AuthRefreshToken:
+		if profile.RefreshToken == "" || profile.ClientID == "" {
+			return nil, fmt.Errorf("auth %s requires ONEDRIVE_REFRESH_TOKEN and ONEDRIVE_CLIENT_ID or their profile settings", auth)
+		}
+		tenantID := profile.TenantID
+		if tenantID == "" {
+			tenantID = "common"
+		}
+		return NewRefreshTokenCredential(cloud.tokenURL(tenantID), profile.ClientID, profile.ClientSecret, profile.RefreshToken, httpClient), nil
+	case AuthDefault:
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: options,
+			TenantID:      profile.TenantID,
+		})
+	default:
+		return nil, fmt.Errorf("invalid auth %q, must be one of %q, %q, %q, %q or %q", auth, AuthToken, AuthDeviceCode, AuthClientSecret, AuthRefreshToken, AuthDefault)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Reasons a downloaded file was deleted.
+const (
+	DeletionRemoved = "removed"
+	DeletionMoved   = "moved"
+)
+
+// Deletion is a line of deletions.ndjson, telling downstream indexes which files to drop.
+type Deletion struct {
+	// ID is the drive item ID of the file, the key of metadata.json.
+	ID string `json:"id"`
+	// Reason is "removed" if the file is no longer synced, or "moved" if it was renamed or moved
+	// and FilePath is its previous location. Moved files are listed as new or updated files too.
+	Reason string `json:"reason"`
+	// FilePath is the path the file was deleted from, relative to the output directory.
+	FilePath    string `json:"filePath"`
+	DisplayName string `json:"displayName,omitempty"`
+	URL         string `json:"url,omitempty"`
+	// Derivatives lists the paths of the derivatives deleted with the file.
+	Derivatives []string  `json:"derivatives,omitempty"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+// recordDeletion notes a deleted file for deletions.ndjson, if it is written.
+func (s *Syncer) recordDeletion(deletion Deletion) {
+	if s.deletionsPath == "" {
+		return
+	}
+	deletion.DeletedAt = time.Now().UTC()
+	s.lock.Lock()
+	s.deletions = append(s.deletions, deletion)
+	s.lock.Unlock()
+}
+
+// writeDeletions appends the deletions of the run to deletionsPath, one JSON object per line.
+// The file keeps growing until the consumer truncates or removes it after processing.
+func (s *Syncer) writeDeletions() error {
+	if s.deletionsPath == "" || len(s.deletions) == 0 {
+		return nil
+	}
+	data, err := encodeDeletions(s.deletions)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.deletionsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	s.deletions = nil
+	return f.Close()
+}
+
+func encodeDeletions(deletions []Deletion) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, deletion := range deletions {
+		if err := encoder.Encode(deletion); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// readDeletions returns the deletions listed in the deletions.ndjson at p, if there is one.
+func readDeletions(p string) ([]Deletion, error) {
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var deletions []Deletion
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		var deletion Deletion
+		if err := decoder.Decode(&deletion); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", p, err)
+		}
+		deletions = append(deletions, deletion)
+	}
+	return deletions, nil
+}
+
+// writeDeletions replaces the deletions.ndjson at p with deletions, removing it if there are none.
+func writeDeletions(p string, deletions []Deletion) error {
+	if len(deletions) == 0 {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := encodeDeletions(deletions)
+	if err != nil {
+		return err
+	}
+	return replaceFile(p, data)
+}
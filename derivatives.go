@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Derivative is a file generated from a downloaded file, such as the text recognized in a scan.
+type Derivative struct {
+	Kind string `json:"kind"`
+	// FilePath is relative to the output directory, like FileDetails.FilePath.
+	FilePath string `json:"filePath"`
+	// SHA256 is the hex encoded hash of the derivative, for deduplication and change detection.
+	SHA256 string `json:"sha256"`
+	// SourceHash is the QuickXorHash of the file the derivative was generated from.
+	SourceHash string `json:"sourceHash,omitempty"`
+}
+
+func newDerivative(kind, filePath string, data []byte, item models.DriveItemable) *Derivative {
+	sum := sha256.Sum256(data)
+	return &Derivative{
+		Kind:       kind,
+		FilePath:   filePath,
+		SHA256:     hex.EncodeToString(sum[:]),
+		SourceHash: remoteHash(item),
+	}
+}
+
+// writeDerivatives generates the derivatives of the file at filePath that are enabled in the config.
+// Failures are logged and leave the derivative out, since the downloaded file itself is still usable.
+func (s *Syncer) writeDerivatives(ctx context.Context, item models.DriveItemable, filePath string) []Derivative {
+	var derivatives []Derivative
+	if len(s.config.OCRCommand) > 0 && needsOCR(item) {
+		if derivative, err := s.runHook(ctx, item, "ocr", s.config.OCRCommand, filePath, filePath+".txt"); err != nil {
+			s.report.warn(WarnDerivativeFailed, *item.GetName(), fmt.Sprintf("OCR failed for %s: %v", filePath, err))
+		} else if derivative != nil {
+			derivatives = append(derivatives, *derivative)
+		}
+	}
+	if len(s.config.TranscribeCommand) > 0 && isMedia(item) {
+		if derivative, err := s.runHook(ctx, item, "transcript", s.config.TranscribeCommand, filePath, filePath+".transcript.txt"); err != nil {
+			s.report.warn(WarnDerivativeFailed, *item.GetName(), fmt.Sprintf("Transcription failed for %s: %v", filePath, err))
+		} else if derivative != nil {
+			derivatives = append(derivatives, *derivative)
+		}
+	}
+	if s.config.ConvertHTML && isHTML(item) {
+		if derivative, err := s.writeMarkdown(item, filePath); err != nil {
+			s.report.warn(WarnDerivativeFailed, *item.GetName(), fmt.Sprintf("Converting %s to Markdown failed: %v", filePath, err))
+		} else {
+			derivatives = append(derivatives, *derivative)
+		}
+	}
+	return derivatives
+}
+
+func (s *Syncer) writeMarkdown(item models.DriveItemable, filePath string) (*Derivative, error) {
+	data, err := os.ReadFile(path.Join(s.outputDir, filePath))
+	if err != nil {
+		return nil, err
+	}
+	markdown, err := htmlToMarkdown(data)
+	if err != nil {
+		return nil, err
+	}
+	outputPath := filePath + ".md"
+	if err := s.writeFile(path.Join(s.outputDir, outputPath), []byte(markdown)); err != nil {
+		return nil, err
+	}
+	return newDerivative("markdown", outputPath, []byte(markdown), item), nil
+}
+
+// runHook runs command on the file at filePath and stores its standard output at outputPath.
+// It returns nil if the command printed nothing.
+func (s *Syncer) runHook(ctx context.Context, item models.DriveItemable, kind string, command []string, filePath, outputPath string) (*Derivative, error) {
+	file := path.Join(s.outputDir, filePath)
+	args := make([]string, 0, len(command)-1)
+	for _, arg := range command[1:] {
+		args = append(args, strings.ReplaceAll(arg, "{file}", file))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, nil
+	}
+
+	if err := s.writeFile(path.Join(s.outputDir, outputPath), stdout.Bytes()); err != nil {
+		return nil, err
+	}
+	logrus.Info(fmt.Sprintf("Wrote %s output for %s", kind, filePath))
+	return newDerivative(kind, outputPath, stdout.Bytes(), item), nil
+}
+
+// needsOCR reports whether item may be a scan. PDFs with a text layer are passed to the OCR
+// command as well, which is expected to return their text.
+func needsOCR(item models.DriveItemable) bool {
+	if item.GetImage() != nil {
+		return true
+	}
+	t := item.GetFile().GetMimeType()
+	return t != nil && (strings.HasPrefix(*t, "image/") || *t == "application/pdf")
+}
+
+// isMedia reports whether item is an audio or video recording.
+func isMedia(item models.DriveItemable) bool {
+	if item.GetAudio() != nil || item.GetVideo() != nil {
+		return true
+	}
+	t := item.GetFile().GetMimeType()
+	return t != nil && (strings.HasPrefix(*t, "audio/") || strings.HasPrefix(*t, "video/"))
+}
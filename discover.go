@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/sites"
+)
+
+type DiscoveredDrive struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Type is personal or business for OneDrives and documentLibrary for SharePoint libraries.
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type DiscoveredSite struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Libraries []DiscoveredDrive `json:"libraries"`
+}
+
+// Discovery lists what the current token can sync, with the identifiers needed to configure sources.
+type Discovery struct {
+	Drives []DiscoveredDrive `json:"drives"`
+	Sites  []DiscoveredSite  `json:"sites"`
+}
+
+// discover prints the drives of the signed-in user and the sites they can access, with their libraries.
+func discover(ctx context.Context, client *msgraphsdk.GraphServiceClient) error {
+	discovery := Discovery{}
+
+	drives, err := withRetry(ctx, graphRetry, func() (models.DriveCollectionResponseable, error) {
+		return client.Me().Drives().Get(ctx, nil)
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		for _, drive := range drives.GetValue() {
+			discovery.Drives = append(discovery.Drives, discoveredDrive(drive))
+		}
+		if drives.GetOdataNextLink() == nil {
+			break
+		}
+		next := *drives.GetOdataNextLink()
+		drives, err = withRetry(ctx, graphRetry, func() (models.DriveCollectionResponseable, error) {
+			return client.Me().Drives().WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Searching for * returns every site the user can access.
+	search := "*"
+	result, err := withRetry(ctx, graphRetry, func() (models.SiteCollectionResponseable, error) {
+		return client.Sites().Get(ctx, &sites.SitesRequestBuilderGetRequestConfiguration{
+			QueryParameters: &sites.SitesRequestBuilderGetQueryParameters{
+				Search: &search,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		for _, site := range result.GetValue() {
+			discovered := DiscoveredSite{
+				ID:   deref(site.GetId()),
+				Name: deref(site.GetDisplayName()),
+				URL:  deref(site.GetWebUrl()),
+			}
+			libraries, err := listSiteDrives(ctx, client, *site.GetId())
+			if err != nil {
+				return err
+			}
+			for _, library := range libraries {
+				discovered.Libraries = append(discovered.Libraries, discoveredDrive(library))
+			}
+			discovery.Sites = append(discovery.Sites, discovered)
+		}
+		if result.GetOdataNextLink() == nil {
+			break
+		}
+		next := *result.GetOdataNextLink()
+		result, err = withRetry(ctx, graphRetry, func() (models.SiteCollectionResponseable, error) {
+			return client.Sites().WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(discovery)
+}
+
+func discoveredDrive(drive models.Driveable) DiscoveredDrive {
+	return DiscoveredDrive{
+		ID:   deref(drive.GetId()),
+		Name: deref(drive.GetName()),
+		Type: deref(drive.GetDriveType()),
+		URL:  deref(drive.GetWebUrl()),
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// freeSpace is not supported on this platform.
+func freeSpace(string) (int64, bool) {
+	return 0, false
+}
+
+// isOutOfSpace reports whether err is a write failing for lack of disk space.
+func isOutOfSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
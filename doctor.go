@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/shares"
+)
+
+// minFreeSpace is the free disk space below which the doctor command fails the disk check.
+const minFreeSpace = 1 << 30
+
+// readPermissions are the Graph permissions that allow reading files, any one of which is enough
+// for shared links and the user's own files. Sites and groups need the .All variants.
+var readPermissions = []string{"Files.Read", "Files.Read.All", "Files.ReadWrite", "Files.ReadWrite.All", "Sites.Read.All", "Sites.ReadWrite.All"}
+
+// doctorCheck is the outcome of one check of the doctor command.
+type doctorCheck struct {
+	name   string
+	err    error
+	detail string
+}
+
+// doctor checks the setup of the workspace: the credentials and their permissions, access to
+// every configured source and the output directory. It prints one line per check and fails if
+// any check failed.
+func doctor(ctx context.Context, client *msgraphsdk.GraphServiceClient) error {
+	workspaceDir := os.Getenv("WORKSPACE_DIR")
+	dataPath := path.Join(workspaceDir, "knowledge", "integrations", "onedrive")
+	var checks []doctorCheck
+	check := func(name string, err error, detail string) {
+		checks = append(checks, doctorCheck{name: name, err: err, detail: detail})
+	}
+
+	config := Config{}
+	if data, err := os.ReadFile(path.Join(dataPath, "config.json")); err == nil {
+		err := json.Unmarshal(data, &config)
+		if err == nil {
+			err = config.validate()
+		}
+		check("config.json", err, "")
+	}
+	externalLinks := map[string]string{}
+	if data, err := os.ReadFile(path.Join(dataPath, "externalLinks.json")); err == nil {
+		check("externalLinks.json", json.Unmarshal(data, &externalLinks), fmt.Sprintf("%d links", len(externalLinks)))
+	}
+
+	token := os.Getenv("GPTSCRIPT_GRAPH_MICROSOFT_COM_BEARER_TOKEN")
+	check("authentication", checkAuthentication(ctx, client, token), "")
+	if claims, ok := decodeToken(token); ok {
+		granted := claims.permissions()
+		var err error
+		if !slices.ContainsFunc(readPermissions, func(p string) bool { return slices.Contains(granted, p) }) {
+			err = fmt.Errorf("none of %s granted", strings.Join(readPermissions, ", "))
+		}
+		check("permissions", err, strings.Join(granted, " "))
+		if expiresAt, ok := tokenExpiry(token); ok {
+			check("token lifetime", nil, "expires "+expiresAt.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+
+	links := make([]string, 0, len(externalLinks))
+	for link := range externalLinks {
+		links = append(links, link)
+	}
+	slices.Sort(links)
+	// Links with a profile of their own are checked with the credentials of that profile, like
+	// during a sync.
+	clients, err := doctorLinkClients(client, config, links)
+	if err != nil || len(config.LinkProfiles) > 0 {
+		check("link profiles", err, "")
+	}
+	for i, link := range links {
+		if clients == nil {
+			break
+		}
+		sharingURL, err := resolveSharingURL(ctx, link)
+		if err == nil {
+			_, err = clients[i].Shares().BySharedDriveItemId(encodeURL(sharingURL)).DriveItem().Get(ctx, &shares.ItemDriveItemRequestBuilderGetRequestConfiguration{
+				QueryParameters: &shares.ItemDriveItemRequestBuilderGetQueryParameters{
+					Select: []string{"id"},
+				},
+			})
+		}
+		check("link "+link, err, "")
+	}
+	for _, site := range config.Sites {
+		key := site.ID
+		var err error
+		if key == "" {
+			key, err = siteKey(site.URL)
+		}
+		if err == nil {
+			_, err = client.Sites().BySiteId(key).Get(ctx, nil)
+		}
+		check("site "+site.source(), err, "")
+	}
+	for _, drive := range config.Drives {
+		_, err := client.Drives().ByDriveId(drive.DriveID).Get(ctx, nil)
+		check("drive "+drive.DriveID, err, "")
+	}
+	for _, list := range config.Lists {
+		key, err := siteKey(list.SiteURL)
+		if err == nil {
+			_, err = client.Sites().BySiteId(key).Lists().ByListId(list.List).Get(ctx, nil)
+		}
+		check("list "+list.source(), err, "")
+	}
+	for _, group := range config.Groups {
+		groupID, err := resolveGroupID(ctx, client, group)
+		if err == nil {
+			_, err = client.Groups().ByGroupId(groupID).Drive().Get(ctx, nil)
+		}
+		check("group "+group, err, "")
+	}
+	for _, channel := range config.Channels {
+		_, err := client.Teams().ByTeamId(channel.TeamID).Channels().ByChannelId(channel.ChannelID).FilesFolder().Get(ctx, nil)
+		check("channel "+channel.ChannelID, err, "")
+	}
+	if config.MyDrive || config.SharedWithMe || config.Search != "" {
+		_, err := client.Me().Drive().Get(ctx, nil)
+		check("your OneDrive", err, "")
+	}
+
+	// The checks above are not retried, so any throttling shows up as a failed check.
+	var throttled error
+	for _, c := range checks {
+		if statusCode(c.err) == http.StatusTooManyRequests {
+			throttled = errors.New("requests are being throttled, lower concurrency or retry later")
+			break
+		}
+	}
+	check("throttling", throttled, "")
+
+	outputDir, err := config.outputPath(workspaceDir, dataPath)
+	var existing string
+	if err == nil {
+		existing, err = checkCreatable(outputDir)
+	}
+	check("output directory", err, outputDir)
+	if err == nil {
+		if free, ok := freeSpace(existing); ok {
+			var err error
+			if free < minFreeSpace {
+				err = fmt.Errorf("only %s free", formatBytes(free))
+			}
+			check("disk space", err, formatBytes(free)+" free")
+		}
+	}
+
+	var failed int
+	for _, c := range checks {
+		status := "PASS"
+		if c.err != nil {
+			status = "FAIL"
+			failed++
+		}
+		line := fmt.Sprintf("%s %s", status, c.name)
+		if c.err != nil {
+			line += ": " + c.err.Error()
+		} else if c.detail != "" {
+			line += ": " + c.detail
+		}
+		fmt.Println(line)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// doctorLinkClients returns the clients to check links with, using the link profiles of config.
+func doctorLinkClients(client *msgraphsdk.GraphServiceClient, config Config, links []string) ([]*msgraphsdk.GraphServiceClient, error) {
+	transportConfig, err := transportConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := transportConfig.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("GPTSCRIPT_GRAPH_MICROSOFT_COM_BEARER_TOKEN")
+	return linkClients(client, config, links, token, transport, transportConfig)
+}
+
+// checkCreatable checks that dir can be written to, or created if it does not exist yet, without
+// creating it. It returns the nearest existing directory, dir itself or one of its parents.
+func checkCreatable(dir string) (string, error) {
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return "", &OutputDirError{Dir: dir, Err: fmt.Errorf("%s is not a directory", existing)}
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", &OutputDirError{Dir: dir, Err: err}
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", &OutputDirError{Dir: dir, Err: err}
+		}
+		existing = parent
+	}
+	f, err := os.CreateTemp(existing, ".write-check-*")
+	if err != nil {
+		return "", &OutputDirError{Dir: dir, Err: err}
+	}
+	f.Close()
+	return existing, os.Remove(f.Name())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// downloadClient downloads content from the pre-authenticated download URLs, which need no Graph
+// authentication. main replaces it with one using the configured transport.
+var downloadClient = http.DefaultClient
+
+// downloadContent downloads the content of item from its pre-authenticated download URL and
+// verifies it against its hash. The URL returned with the listing is used if it has not expired
+// yet, which saves a Graph request per file.
+func (s *Syncer) downloadContent(ctx context.Context, item models.DriveItemable) ([]byte, error) {
+	download := func(u string) ([]byte, error) {
+		return withRetry(ctx, contentRetry, func() ([]byte, error) {
+			data, err := getContent(ctx, u, "")
+			if err != nil {
+				return nil, err
+			}
+			if err := verifyHash(item, data); err != nil {
+				// The file may have been changed after it was listed, in which case the retry
+				// downloads the current version instead.
+				if current, urlErr := s.downloadURL(ctx, item); urlErr == nil {
+					u = current
+				}
+				return nil, err
+			}
+			return data, nil
+		})
+	}
+
+	if u, ok := item.GetAdditionalData()[downloadURLKey].(*string); ok && u != nil {
+		data, err := download(*u)
+		// Listed URLs expire after about an hour, long before a large sync gets to every file.
+		if code := statusCode(err); code != http.StatusUnauthorized && code != http.StatusForbidden {
+			return data, err
+		}
+	}
+
+	u, err := s.downloadURL(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	return download(u)
+}
+
+// getContent downloads the content at downloadURL, limited to byteRange if it is set.
+func getContent(ctx context.Context, downloadURL, byteRange string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	expected := http.StatusOK
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+		expected = http.StatusPartialContent
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		headers := abstractions.NewResponseHeaders()
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			headers.Add("Retry-After", v)
+		}
+		return nil, &abstractions.ApiError{
+			Message:            fmt.Sprintf("unexpected status %s downloading %s", resp.Status, byteRangeOrAll(byteRange)),
+			ResponseStatusCode: resp.StatusCode,
+			ResponseHeaders:    headers,
+		}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func byteRangeOrAll(byteRange string) string {
+	if byteRange == "" {
+		return "content"
+	}
+	return byteRange
+}
+
+// reconcile fetches item again and copies the properties that change with its content into it,
+// so that the download, its verification and the recorded metadata all refer to the current
+// version.
+func (s *Syncer) reconcile(ctx context.Context, item models.DriveItemable) error {
+	fresh, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return itemRequest(s.clientFor(item), item).Get(ctx, &drives2.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemItemsDriveItemItemRequestBuilderGetQueryParameters{
+				Select: driveItemFields,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	additionalData := item.GetAdditionalData()
+	if additionalData == nil {
+		additionalData = map[string]any{}
+		item.SetAdditionalData(additionalData)
+	}
+	additionalData[downloadURLKey] = fresh.GetAdditionalData()[downloadURLKey]
+
+	if fresh.GetETag() == nil || (item.GetETag() != nil && *fresh.GetETag() == *item.GetETag()) {
+		return nil
+	}
+	logrus.Info(fmt.Sprintf("%s changed since it was listed, syncing the current version", *item.GetName()))
+	item.SetName(fresh.GetName())
+	item.SetETag(fresh.GetETag())
+	item.SetCTag(fresh.GetCTag())
+	item.SetSize(fresh.GetSize())
+	item.SetFile(fresh.GetFile())
+	item.SetWebUrl(fresh.GetWebUrl())
+	item.SetParentReference(fresh.GetParentReference())
+	item.SetLastModifiedDateTime(fresh.GetLastModifiedDateTime())
+	item.SetLastModifiedBy(fresh.GetLastModifiedBy())
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// DriveSource is a folder or file in a drive, addressed directly instead of through a shared link.
+// The drive IDs the token can access are listed by the discover command.
+type DriveSource struct {
+	DriveID string `json:"driveId"`
+	// Path is the path of the folder or file from the root of the drive, e.g. /Projects/Docs. The
+	// whole drive is synced if it is empty or /.
+	Path string `json:"path,omitempty"`
+}
+
+// source identifies the location in the sources of the files found through it.
+func (d DriveSource) source() string {
+	return "drives/" + d.DriveID + "/root:/" + strings.Trim(d.Path, "/")
+}
+
+// getItemsForDrivePath returns the files at the location of source.
+func getItemsForDrivePath(ctx context.Context, client *msgraphsdk.GraphServiceClient, source DriveSource) ([]models.DriveItemable, error) {
+	p := strings.Trim(source.Path, "/")
+	if p == "" {
+		return getItemsForDrive(ctx, client, source.DriveID)
+	}
+
+	return getItemsForRelativePath(ctx, client, source.DriveID, "root", p)
+}
+
+// getItemsForRelativePath returns the files at path p below the item with itemID, or "root", in
+// the drive with driveID.
+func getItemsForRelativePath(ctx context.Context, client *msgraphsdk.GraphServiceClient, driveID, itemID, p string) ([]models.DriveItemable, error) {
+	// The SDK has no builder for path-based addressing, and escapes the slashes of a path passed
+	// as item ID, so the item is looked up by a URL of its own first.
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	base := "/root"
+	if itemID != "root" {
+		base = "/items/" + url.PathEscape(itemID)
+	}
+	rawURL := client.GetAdapter().GetBaseUrl() + "/drives/" + url.PathEscape(driveID) + base + ":/" + strings.Join(segments, "/") + ":?$select=id"
+	located, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return client.Drives().ByDriveId(driveID).Root().WithUrl(rawURL).Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getItemsForRemoteItem(ctx, client, driveID, *located.GetId())
+}
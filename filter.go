@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// systemFiles holds names of files that operating systems and Office create next to documents.
+var systemFiles = map[string]bool{
+	"desktop.ini": true,
+	"thumbs.db":   true,
+	".ds_store":   true,
+	"icon\r":      true,
+}
+
+// skipReason returns why item must not be synced, or an empty reason if it can be synced.
+func (c Config) skipReason(item models.DriveItemable) SkipReason {
+	if item.GetMalware() != nil {
+		return SkipMalware
+	}
+	if item.GetFile() == nil || item.GetPackageEscaped() != nil {
+		return SkipUnsupported
+	}
+	if c.SkipEmptyFiles && item.GetSize() != nil && *item.GetSize() == 0 {
+		return SkipEmpty
+	}
+	if c.MinFileSize > 0 && item.GetSize() != nil && *item.GetSize() < c.MinFileSize {
+		return SkipTooSmall
+	}
+	if c.MaxFileSize > 0 && item.GetSize() != nil && *item.GetSize() > c.MaxFileSize {
+		return SkipTooLarge
+	}
+	if c.Shortcuts == ShortcutsSkip && isShortcut(item) {
+		return SkipShortcut
+	}
+	if !c.IncludeHidden && isHidden(item) {
+		return SkipHidden
+	}
+	if ext := fileExtension(*item.GetName()); (len(c.Extensions) > 0 && !hasExtension(c.Extensions, ext)) || hasExtension(c.ExcludeExtensions, ext) {
+		return SkipFilteredByExtension
+	}
+	if after, _ := c.modifiedAfter(); !after.IsZero() && item.GetLastModifiedDateTime() != nil && !item.GetLastModifiedDateTime().After(after) {
+		return SkipModifiedBefore
+	}
+	if c.filteredByPattern(item) {
+		return SkipFilteredByPattern
+	}
+	if len(c.Authors) > 0 && !slices.ContainsFunc([]models.IdentitySetable{item.GetCreatedBy(), item.GetLastModifiedBy()}, func(identity models.IdentitySetable) bool {
+		return isAuthor(c.Authors, identity)
+	}) {
+		return SkipFilteredByAuthor
+	}
+	return ""
+}
+
+// fileExtension returns the extension of name without the dot, in lower case.
+func fileExtension(name string) string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+}
+
+// hasExtension reports whether ext is one of extensions, which may be given with a leading dot.
+func hasExtension(extensions []string, ext string) bool {
+	return slices.ContainsFunc(extensions, func(e string) bool {
+		return strings.EqualFold(strings.TrimPrefix(e, "."), ext)
+	})
+}
+
+// isAuthor reports whether the user of identity has one of the given email addresses or UPNs.
+// Graph reports the address of the user in the non-standard email property of the identity.
+func isAuthor(authors []string, identity models.IdentitySetable) bool {
+	if identity == nil || identity.GetUser() == nil {
+		return false
+	}
+	user := identity.GetUser()
+	var names []string
+	for _, key := range []string{"email", "userPrincipalName"} {
+		if v, ok := user.GetAdditionalData()[key].(*string); ok && v != nil {
+			names = append(names, *v)
+		}
+	}
+	return slices.ContainsFunc(authors, func(author string) bool {
+		return slices.ContainsFunc(names, func(name string) bool {
+			return strings.EqualFold(author, name)
+		})
+	})
+}
+
+// isHidden reports whether item is a system file, an Office lock file, or a dotfile or lives in a dot folder.
+func isHidden(item models.DriveItemable) bool {
+	name := strings.ToLower(*item.GetName())
+	if systemFiles[name] || strings.HasPrefix(name, "~$") {
+		return true
+	}
+	for _, segment := range strings.Split(getDisplayName(item), "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// folderURL returns the webUrl of the folder containing item. The URL recorded in detail is
+// reused as long as the item has not moved, and every folder is looked up at most once per run.
+func (s *Syncer) folderURL(ctx context.Context, item models.DriveItemable, detail FileDetails) string {
+	if detail.FolderURL != "" && detail.DisplayName == getDisplayName(item) {
+		return detail.FolderURL
+	}
+	parent := item.GetParentReference()
+	if parent == nil || parent.GetDriveId() == nil || parent.GetId() == nil {
+		return ""
+	}
+
+	key := *parent.GetDriveId() + "/" + *parent.GetId()
+	s.lock.Lock()
+	u, ok := s.folderURLs[key]
+	s.lock.Unlock()
+	if ok {
+		return u
+	}
+
+	folder, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return s.clientFor(item).Drives().ByDriveId(*parent.GetDriveId()).Items().ByDriveItemId(*parent.GetId()).Get(ctx, &drives.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives.ItemItemsDriveItemItemRequestBuilderGetQueryParameters{
+				Select: []string{"webUrl"},
+			},
+		})
+	})
+	if err != nil {
+		s.report.warn(WarnFolderUnavailable, *item.GetName(), fmt.Sprintf("Could not look up the folder of %s: %v", *item.GetName(), err))
+		return ""
+	}
+	if folder.GetWebUrl() != nil {
+		u = normalizeWebURL(*folder.GetWebUrl())
+	}
+
+	s.lock.Lock()
+	s.folderURLs[key] = u
+	s.lock.Unlock()
+	return u
+}
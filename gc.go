@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotRetention limits the snapshots kept by the gc command. The latest snapshot is always
+// kept, and a snapshot is removed as soon as it exceeds any of the limits.
+type SnapshotRetention struct {
+	// MaxAgeDays removes snapshots of runs started more than this many days ago.
+	MaxAgeDays int `json:"maxAgeDays,omitempty"`
+	// MaxCount keeps at most this many snapshots, the latest ones.
+	MaxCount int `json:"maxCount,omitempty"`
+	// MaxSizeMB keeps the latest snapshots up to this total size in megabytes. Files hard linked
+	// between snapshots count in each of them, so this overestimates the space used.
+	MaxSizeMB int64 `json:"maxSizeMB,omitempty"`
+}
+
+// gc removes what the sync leaves behind and no longer needs: partial downloads of files that
+// are not synced anymore, and the snapshots outside SnapshotRetention.
+func gc() error {
+	workspaceDir := os.Getenv("WORKSPACE_DIR")
+	dataPath := path.Join(workspaceDir, "knowledge", "integrations", "onedrive")
+
+	config := Config{}
+	if data, err := os.ReadFile(path.Join(dataPath, "config.json")); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	metadata := map[string]FileDetails{}
+	if data, err := os.ReadFile(path.Join(dataPath, "metadata.json")); err == nil {
+		if err := decodeMetadata(data, &metadata); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	outputDir, err := config.outputPath(workspaceDir, dataPath)
+	if err != nil {
+		return err
+	}
+	removed, err := removeStalePartials(outputDir, config.TempDir, metadata)
+	if err != nil {
+		return err
+	}
+	logrus.Info(fmt.Sprintf("Removed %d stale partial downloads", removed))
+
+	snapshotDir, err := config.snapshotPath(workspaceDir)
+	if err != nil || snapshotDir == "" {
+		return err
+	}
+	removed, err = pruneSnapshots(snapshotDir, config.SnapshotRetention, time.Now())
+	if err != nil {
+		return err
+	}
+	logrus.Info(fmt.Sprintf("Removed %d snapshots", removed))
+	return nil
+}
+
+// removeStalePartials removes the .partial files of chunked downloads in outputDir and in the
+// partialsDir of tempDir, if set, that no synced file would resume. Other files in tempDir are
+// left alone, since it may be shared with other programs.
+func removeStalePartials(outputDir, tempDir string, metadata map[string]FileDetails) (int, error) {
+	dirs := []string{outputDir}
+	if tempDir != "" {
+		dirs = append(dirs, filepath.Join(tempDir, partialsDir))
+	}
+	wanted := map[string]bool{}
+	for id, detail := range metadata {
+		localPath := filepath.Join(outputDir, filepath.FromSlash(detail.localPath(id)))
+		// A synced file may itself be named .partial.
+		wanted[localPath] = true
+		if detail.Sync {
+			wanted[localPath+".partial"] = true
+			if tempDir != "" {
+				wanted[filepath.Join(tempDir, partialsDir, id+".partial")] = true
+			}
+		}
+	}
+
+	var removed int
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(p, ".partial") || wanted[p] {
+				return nil
+			}
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			logrus.Info(fmt.Sprintf("Removed %s", p))
+			removed++
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// pruneSnapshots removes the snapshots in snapshotDir outside retention, oldest first.
+func pruneSnapshots(snapshotDir string, retention *SnapshotRetention, now time.Time) (int, error) {
+	if retention == nil {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(snapshotDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	type snapshot struct {
+		name      string
+		startedAt time.Time
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		// Anything not named like a snapshot was put there by someone else and is left alone.
+		startedAt, err := time.Parse(snapshotLayout, entry.Name())
+		if entry.IsDir() && err == nil {
+			snapshots = append(snapshots, snapshot{name: entry.Name(), startedAt: startedAt})
+		}
+	}
+	// Latest first, so the limits keep the latest snapshots.
+	slices.SortFunc(snapshots, func(a, b snapshot) int {
+		return b.startedAt.Compare(a.startedAt)
+	})
+
+	var total int64
+	var removed int
+	for i, s := range snapshots {
+		dir := filepath.Join(snapshotDir, s.name)
+		size, err := dirSize(dir)
+		if err != nil {
+			return removed, err
+		}
+		total += size
+		if i == 0 {
+			continue
+		}
+		if (retention.MaxAgeDays > 0 && now.Sub(s.startedAt) > time.Duration(retention.MaxAgeDays)*24*time.Hour) ||
+			(retention.MaxCount > 0 && i >= retention.MaxCount) ||
+			(retention.MaxSizeMB > 0 && total > retention.MaxSizeMB<<20) {
+			if err := os.RemoveAll(dir); err != nil {
+				return removed, err
+			}
+			logrus.Info(fmt.Sprintf("Removed snapshot %s", dir))
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// dirSize returns the total size of the files below dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
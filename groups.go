@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/groups"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// getItemsForGroups returns the files in the drives of the Microsoft 365 groups the user is a
+// member of, limited to groups whose display name contains filter if it is set.
+func getItemsForGroups(ctx context.Context, client *msgraphsdk.GraphServiceClient, filter string) (map[string][]models.DriveItemable, error) {
+	groups, err := withRetry(ctx, graphRetry, func() (models.GroupCollectionResponseable, error) {
+		return client.Me().MemberOf().GraphGroup().Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string][]models.DriveItemable{}
+	for {
+		for _, group := range groups.GetValue() {
+			// Only Microsoft 365 groups have a drive, security groups and distribution lists do not.
+			if !slices.Contains(group.GetGroupTypes(), "Unified") {
+				continue
+			}
+			name := deref(group.GetDisplayName())
+			if filter != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(filter)) {
+				continue
+			}
+			driveURL, children, err := getItemsForGroupDrive(ctx, client, *group.GetId(), name)
+			if err != nil {
+				return nil, err
+			}
+			result[driveURL] = children
+		}
+		if groups.GetOdataNextLink() == nil {
+			return result, nil
+		}
+		next := *groups.GetOdataNextLink()
+		groups, err = withRetry(ctx, graphRetry, func() (models.GroupCollectionResponseable, error) {
+			return client.Me().MemberOf().GraphGroup().WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getItemsForGroup returns the files in the drive of a Microsoft 365 group given by ID or display
+// name, with the webUrl of the drive to attribute them to. The user does not need to be a member
+// of the group if they have access to its drive otherwise.
+func getItemsForGroup(ctx context.Context, client *msgraphsdk.GraphServiceClient, group string) (string, []models.DriveItemable, error) {
+	groupID, err := resolveGroupID(ctx, client, group)
+	if err != nil {
+		return "", nil, err
+	}
+	return getItemsForGroupDrive(ctx, client, groupID, group)
+}
+
+// resolveGroupID returns the ID of a group given by ID or by its display name, which must be unique.
+func resolveGroupID(ctx context.Context, client *msgraphsdk.GraphServiceClient, group string) (string, error) {
+	if _, err := uuid.Parse(group); err == nil {
+		return group, nil
+	}
+
+	filter := fmt.Sprintf("displayName eq '%s'", strings.ReplaceAll(group, "'", "''"))
+	found, err := withRetry(ctx, graphRetry, func() (models.GroupCollectionResponseable, error) {
+		return client.Groups().Get(ctx, &groups.GroupsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &groups.GroupsRequestBuilderGetQueryParameters{
+				Filter: &filter,
+				Select: []string{"id", "displayName"},
+			},
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	switch len(found.GetValue()) {
+	case 0:
+		return "", fmt.Errorf("no group named %q", group)
+	case 1:
+		return *found.GetValue()[0].GetId(), nil
+	default:
+		return "", fmt.Errorf("several groups are named %q, use the ID of the group instead", group)
+	}
+}
+
+// getItemsForGroupDrive returns the files in the drive of the group with groupID, with the
+// webUrl of the drive.
+func getItemsForGroupDrive(ctx context.Context, client *msgraphsdk.GraphServiceClient, groupID, name string) (string, []models.DriveItemable, error) {
+	drive, err := withRetry(ctx, graphRetry, func() (models.Driveable, error) {
+		return client.Groups().ByGroupId(groupID).Drive().Get(ctx, nil)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	children, err := getItemsForDrive(ctx, client, *drive.GetId())
+	if err != nil {
+		return "", nil, err
+	}
+	logrus.Info(fmt.Sprintf("Found %d files in group %s", len(children), name))
+	return deref(drive.GetWebUrl()), children, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// stopwords holds frequent function words per language. Counting them is crude but needs no
+// models and is reliable for anything longer than a few sentences.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "as", "was", "on", "are", "this", "be"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "sich", "den", "ein", "eine", "auf", "ich", "zu", "von", "des"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "dans", "que", "pour", "pas", "sur", "du", "qui", "au", "avec"},
+	"es": {"el", "la", "los", "las", "y", "que", "de", "en", "es", "por", "una", "con", "para", "del", "se", "no"},
+	"it": {"il", "di", "che", "la", "è", "per", "una", "non", "sono", "con", "del", "della", "gli", "le", "si", "anche"},
+	"pt": {"o", "a", "os", "que", "de", "não", "uma", "com", "para", "do", "da", "em", "se", "são", "por", "mais"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "ook", "er", "maar"},
+}
+
+const (
+	// languageSampleSize is how much of a file is looked at.
+	languageSampleSize = 64 * 1024
+	// minLanguageHits is the number of stopwords needed before a language is reported.
+	minLanguageHits = 5
+)
+
+// detectLanguage returns the ISO 639-1 code of the most likely language of text, or an empty string if unsure.
+func detectLanguage(text string) string {
+	if len(text) > languageSampleSize {
+		text = text[:languageSampleSize]
+	}
+
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for lang, words := range stopwords {
+			for _, w := range words {
+				if w == word {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestCount, secondCount := "", 0, 0
+	for lang, count := range counts {
+		switch {
+		case count > bestCount:
+			best, bestCount, secondCount = lang, count, bestCount
+		case count > secondCount:
+			secondCount = count
+		}
+	}
+	// Related languages share many stopwords, so require a clear winner.
+	if bestCount < minLanguageHits || bestCount == secondCount {
+		return ""
+	}
+	return best
+}
+
+// isText reports whether item holds plain text that language detection can read directly.
+func isText(item models.DriveItemable) bool {
+	if t := item.GetFile().GetMimeType(); t != nil && strings.HasPrefix(*t, "text/") {
+		return true
+	}
+	switch strings.ToLower(path.Ext(*item.GetName())) {
+	case ".txt", ".md", ".csv", ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// readSample returns the start of the file at p that detectLanguage looks at, without reading
+// all of a large file.
+func readSample(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, languageSampleSize))
+}
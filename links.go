@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/shares"
+)
+
+// getItemsForLinks resolves the shared links and lists their files, up to concurrency links at a
+// time, each link with the client at its index and limited to the subfolder in paths, if any. The
+// files of each link are returned at the link's index, so the caller can merge them in a stable
+// order. The first error stops the links not yet started.
+func getItemsForLinks(ctx context.Context, clients []*msgraphsdk.GraphServiceClient, links []string, paths map[string]string, concurrency int) ([][]models.DriveItemable, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		firstErr error
+		result   = make([][]models.DriveItemable, len(links))
+		workers  = make(chan struct{}, concurrency)
+	)
+	for i, link := range links {
+		workers <- struct{}{}
+		lock.Lock()
+		stop := firstErr != nil
+		lock.Unlock()
+		if stop {
+			<-workers
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			children, err := getItemsForLinkPath(ctx, clients[i], link, paths[link])
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			result[i] = children
+		}(i, link)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// getItemsForLink resolves a shared link and returns the files it points to.
+func getItemsForLink(ctx context.Context, client *msgraphsdk.GraphServiceClient, link string) ([]models.DriveItemable, error) {
+	return getItemsForLinkPath(ctx, client, link, "")
+}
+
+// getItemsForLinkPath resolves a shared link and returns the files at path p below the folder
+// it points to, or all of them if p is empty.
+func getItemsForLinkPath(ctx context.Context, client *msgraphsdk.GraphServiceClient, link, p string) ([]models.DriveItemable, error) {
+	configuration := &shares.ItemDriveItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &shares.ItemDriveItemRequestBuilderGetQueryParameters{
+			Select: driveItemFields,
+			Expand: []string{expandChildren},
+		},
+	}
+	sharingURL, err := resolveSharingURL(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	shareDriveItem, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return client.Shares().BySharedDriveItemId(encodeURL(sharingURL)).DriveItem().Get(ctx, configuration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strings.Trim(p, "/") != "" {
+		return getItemsForRelativePath(ctx, client, *shareDriveItem.GetParentReference().GetDriveId(), *shareDriveItem.GetId(), p)
+	}
+	return getChildrenFileForItem(ctx, client, shareDriveItem)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// ListItemDetails holds the SharePoint columns of a file in a document library.
+type ListItemDetails struct {
+	ContentType string `json:"contentType,omitempty"`
+	// Fields maps column names, including custom columns, to their values.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// listItemDetails returns the columns of item. They are only fetched again when the item has
+// changed, which includes edits to its columns. Files outside SharePoint have no columns.
+func (s *Syncer) listItemDetails(ctx context.Context, item models.DriveItemable, detail FileDetails) *ListItemDetails {
+	if detail.ListItem != nil && sameTimestamp(detail.UpdatedAt, *item.GetLastModifiedDateTime()) {
+		return detail.ListItem
+	}
+
+	listItem, err := withRetry(ctx, graphRetry, func() (models.ListItemable, error) {
+		return itemRequest(s.clientFor(item), item).ListItem().Get(ctx, &drives2.ItemItemsItemListItemRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemItemsItemListItemRequestBuilderGetQueryParameters{
+				Expand: []string{"fields"},
+			},
+		})
+	})
+	if err != nil {
+		switch statusCode(err) {
+		case http.StatusNotFound, http.StatusBadRequest:
+		default:
+			s.report.warn(WarnColumnsUnavailable, *item.GetName(), fmt.Sprintf("Could not get the columns of %s: %v", *item.GetName(), err))
+		}
+		return nil
+	}
+
+	result := &ListItemDetails{}
+	if contentType := listItem.GetContentType(); contentType != nil && contentType.GetName() != nil {
+		result.ContentType = *contentType.GetName()
+	}
+	if fields := listItem.GetFields(); fields != nil {
+		result.Fields = map[string]any{}
+		for name, value := range fields.GetAdditionalData() {
+			if !strings.HasPrefix(name, "@odata.") {
+				result.Fields[name] = value
+			}
+		}
+	}
+	return result
+}
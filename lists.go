@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/sites"
+)
+
+// ListSource is a SharePoint list whose rows link to the documents to sync, such as a curated
+// reading list.
+type ListSource struct {
+	// SiteURL is the site the list belongs to, e.g. https://contoso.sharepoint.com/sites/hr
+	SiteURL string `json:"siteUrl"`
+	// List is the name or ID of the list.
+	List string `json:"list"`
+	// Column is the internal name of the column holding the document URL, either a hyperlink or a text column.
+	Column string `json:"column"`
+}
+
+// source identifies the list in the sources of the files found through it.
+func (l ListSource) source() string {
+	return l.SiteURL + "/Lists/" + url.PathEscape(l.List)
+}
+
+// getItemsForList returns the files linked from the rows of a list. Rows without a link are
+// ignored, and links that cannot be resolved are logged and skipped so one stale row does not
+// stop the sync. complete is false if any link was skipped, in which case the files previously
+// synced from the list are kept.
+func getItemsForList(ctx context.Context, client *msgraphsdk.GraphServiceClient, source ListSource, report *SyncReport) (result []models.DriveItemable, complete bool, err error) {
+	key, err := siteKey(source.SiteURL)
+	if err != nil {
+		return nil, false, err
+	}
+	site, err := withRetry(ctx, graphRetry, func() (models.Siteable, error) {
+		return client.Sites().BySiteId(key).Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	builder := client.Sites().BySiteId(*site.GetId()).Lists().ByListId(source.List).Items()
+	rows, err := withRetry(ctx, graphRetry, func() (models.ListItemCollectionResponseable, error) {
+		return builder.Get(ctx, &sites.ItemListsItemItemsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &sites.ItemListsItemItemsRequestBuilderGetQueryParameters{
+				Expand: []string{"fields($select=" + source.Column + ")"},
+			},
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	complete = true
+	for {
+		for _, row := range rows.GetValue() {
+			link := linkField(row, source.Column)
+			if link == "" {
+				continue
+			}
+			children, err := getItemsForLink(ctx, client, link)
+			if err != nil {
+				report.warn(WarnLinkSkipped, "", fmt.Sprintf("Skipping %s linked from list %s: %v", link, source.List, err))
+				complete = false
+				continue
+			}
+			result = append(result, children...)
+		}
+		if rows.GetOdataNextLink() == nil {
+			return result, complete, nil
+		}
+		next := *rows.GetOdataNextLink()
+		rows, err = withRetry(ctx, graphRetry, func() (models.ListItemCollectionResponseable, error) {
+			return builder.WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// linkField returns the URL in column of row. Hyperlink columns hold an object with the URL and
+// its description, text columns the URL itself.
+func linkField(row models.ListItemable, column string) string {
+	if row.GetFields() == nil {
+		return ""
+	}
+	switch v := row.GetFields().GetAdditionalData()[column].(type) {
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case map[string]any:
+		if u, ok := v["Url"].(*string); ok && u != nil {
+			return *u
+		}
+	}
+	return ""
+}
@@ -4,17 +4,20 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
 	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
-	"github.com/microsoftgraph/msgraph-sdk-go/shares"
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,174 +40,537 @@ func (s StaticTokenCredential) GetToken(ctx context.Context, options policy.Toke
 type FileDetails struct {
 	FileName    string `json:"fileName"`
 	DisplayName string `json:"displayName"`
-	URL         string `json:"url"`
-	UpdatedAt   string `json:"updatedAt"`
-	Sync        bool   `json:"sync"`
+	// URL is the webUrl of the file, without session and tracking parameters.
+	URL string `json:"url"`
+	// UpdatedAt is the last modification time in Config.TimestampFormat.
+	UpdatedAt string `json:"updatedAt"`
+	Sync      bool   `json:"sync"`
+	// ItemURL is the Graph URL of the file by its immutable IDs, which keeps working when URL changes.
+	ItemURL string `json:"itemUrl,omitempty"`
+	// FolderURL is the webUrl of the folder containing the file, for linking to it.
+	FolderURL string `json:"folderUrl,omitempty"`
+	// FilePath is where the file was downloaded to, relative to the output directory.
+	FilePath string `json:"filePath,omitempty"`
+	// QuickXorHash is the hash of the downloaded content as reported by Graph.
+	QuickXorHash string `json:"quickXorHash,omitempty"`
+	// CTag identifies the version of the content that was downloaded.
+	CTag string `json:"cTag,omitempty"`
+	// ETag identifies the version of the item, including its metadata, when it was last seen.
+	ETag string `json:"eTag,omitempty"`
+	// Sources lists the links and sites the file is reachable through, the primary one first.
+	Sources []string `json:"sources,omitempty"`
+	// Language is the detected ISO 639-1 code of text content, if language detection is enabled.
+	Language string `json:"language,omitempty"`
+	// Derivatives lists the files generated from the downloaded file.
+	Derivatives []Derivative  `json:"derivatives,omitempty"`
+	Photo       *PhotoDetails `json:"photo,omitempty"`
+	// Description is the description of the file set in OneDrive or SharePoint.
+	Description string           `json:"description,omitempty"`
+	ListItem    *ListItemDetails `json:"listItem,omitempty"`
+	// Properties holds the drive item properties listed in Config.ItemProperties, as Graph returned them.
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// localPath returns the stored relative path of the downloaded copy of item id. Metadata written
+// before FilePath was recorded always downloaded to <id>/<fileName>.
+func (d FileDetails) localPath(id string) string {
+	if d.FilePath != "" {
+		return d.FilePath
+	}
+	return path.Join(id, d.FileName)
 }
 
 func main() {
-	cred := NewStaticTokenCredential(os.Getenv("GPTSCRIPT_GRAPH_MICROSOFT_COM_BEARER_TOKEN"))
-	client, err := msgraphsdk.NewGraphServiceClientWithCredentials(cred, []string{})
+	token := os.Getenv("GPTSCRIPT_GRAPH_MICROSOFT_COM_BEARER_TOKEN")
+	transportConfig, err := transportConfigFromEnv()
 	if err != nil {
-		logrus.Error(err)
-		os.Exit(1)
+		exitWithError(err)
+	}
+	transport, err := transportConfig.newTransport()
+	if err != nil {
+		exitWithError(err)
+	}
+	downloadClient = &http.Client{Transport: transport}
+	profile, err := profileFromEnv()
+	if err != nil {
+		exitWithError(err)
+	}
+	cloud, err := cloudByName(profile.Cloud)
+	if err != nil {
+		exitWithError(err)
+	}
+	cred, err := newCredential(token, downloadClient, profile, cloud)
+	if err != nil {
+		exitWithError(err)
+	}
+	client, err := newGraphClient(cred, transport, transportConfig, cloud)
+	if err != nil {
+		exitWithError(err)
 	}
 	ctx := context.Background()
 
+	if len(os.Args) > 1 && !isToolMode() && !isCheckMode() {
+		if err := runCommand(ctx, client, os.Args[1]); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
 	metadata := map[string]FileDetails{}
 	externalLinks := map[string]string{}
+	config := Config{}
 	dataPath := path.Join(os.Getenv("WORKSPACE_DIR"), "knowledge", "integrations", "onedrive")
 	metadataPath := path.Join(dataPath, "metadata.json")
 	externalLinkPath := path.Join(dataPath, "externalLinks.json")
+	configPath := path.Join(dataPath, "config.json")
+	reportPath := path.Join(dataPath, "report.json")
+	statusPath := path.Join(dataPath, ".sync-status.json")
+	// A check only reads the workspace.
+	if !isCheckMode() {
+		if err := bootstrap(dataPath); err != nil {
+			exitWithError(err)
+		}
+	}
+	// The settings of config.json are read over the defaults of the profile.
+	if len(profile.Defaults) > 0 {
+		if err := json.Unmarshal(profile.Defaults, &config); err != nil {
+			exitWithError(fmt.Errorf("invalid defaults of profile %s: %w", os.Getenv("ONEDRIVE_PROFILE"), err))
+		}
+	}
 	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
-		err := os.MkdirAll(dataPath, 0755)
-		if err != nil {
-			logrus.Error(err)
-			os.Exit(1)
+		if !isCheckMode() {
+			if err := os.MkdirAll(dataPath, 0755); err != nil {
+				exitWithError(err)
+			}
 		}
 	} else {
 		if _, err := os.Stat(metadataPath); err == nil {
 			data, err := os.ReadFile(metadataPath)
 			if err != nil {
-				logrus.Error(err)
-				os.Exit(1)
+				exitWithError(err)
 			}
 
-			err = json.Unmarshal(data, &metadata)
+			err = decodeMetadata(data, &metadata)
 			if err != nil {
-				logrus.Error(err)
-				os.Exit(1)
+				exitWithError(err)
 			}
 		}
 
 		if _, err := os.Stat(externalLinkPath); err == nil {
 			data, err := os.ReadFile(externalLinkPath)
 			if err != nil {
-				logrus.Error(err)
-				os.Exit(1)
+				exitWithError(err)
 			}
 
 			err = json.Unmarshal(data, &externalLinks)
 			if err != nil {
-				logrus.Error(err)
-				os.Exit(1)
+				exitWithError(err)
 			}
 		}
+
+		if _, err := os.Stat(configPath); err == nil {
+			data, err := os.ReadFile(configPath)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			err = json.Unmarshal(data, &config)
+			if err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+
+	if isToolMode() {
+		if err := applyToolArguments(&config); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	if err := config.validate(); err != nil {
+		exitWithError(err)
+	}
+	graphRetry = config.GraphRetry.apply(graphRetry)
+	contentRetry = config.ContentRetry.apply(contentRetry)
+	selectItemProperties(config.ItemProperties)
+	maxDepth = config.MaxDepth
+
+	outputDir, err := config.outputPath(os.Getenv("WORKSPACE_DIR"), dataPath)
+	if err != nil {
+		exitWithError(err)
+	}
+	snapshotDir, err := config.snapshotPath(os.Getenv("WORKSPACE_DIR"))
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Fail before resolving any links if the files could not be written anyway.
+	report := NewSyncReport()
+	previous := SyncReport{}
+	if data, err := os.ReadFile(reportPath); err == nil {
+		if err := json.Unmarshal(data, &previous); err != nil {
+			logrus.Warn(fmt.Sprintf("Ignoring unreadable %s: %v", reportPath, err))
+		}
+	}
+	checkTokenLifetime(report, token, time.Duration(previous.DurationSeconds*float64(time.Second)))
+
+	for _, dir := range []string{outputDir, config.TempDir} {
+		if dir == "" || isCheckMode() {
+			continue
+		}
+		if err := checkWritable(dir); err != nil {
+			exitWithReport(report, reportPath, ErrOutputNotWritable, err)
+		}
+	}
+	var authErr *AuthError
+	if err := checkAuthentication(ctx, client, token); errors.As(err, &authErr) {
+		exitWithReport(report, reportPath, authErr.Code, err)
+	}
+	if !isCheckMode() {
+		writeStatus(statusPath, SyncStatus{Status: StatusListing, StartedAt: report.StartedAt})
 	}
 
 	items := map[string]models.DriveItemable{}
+	sources := map[string][]string{}
+	// incomplete holds the sources that could only be listed in part, whose files are not pruned.
+	incomplete := map[string]bool{}
+
+	// Links are visited in a stable order so that an item reachable via several of them is always
+	// attributed to the same primary source.
+	links := make([]string, 0, len(externalLinks))
 	for link := range externalLinks {
-		requestParameters := &shares.ItemDriveItemRequestBuilderGetQueryParameters{
-			Expand: []string{"children"},
+		links = append(links, link)
+	}
+	slices.Sort(links)
+	clients, err := linkClients(client, config, links, token, transport, transportConfig)
+	if err != nil {
+		exitWithError(err)
+	}
+	linkItems, err := getItemsForLinks(ctx, clients, links, config.LinkPaths, config.concurrency())
+	if err != nil {
+		exitWithError(err)
+	}
+	driveClients := map[string]*msgraphsdk.GraphServiceClient{}
+	for i, link := range links {
+		addItems(items, sources, report, config, link, linkItems[i])
+		if clients[i] != client {
+			for _, item := range linkItems[i] {
+				driveClients[*item.GetParentReference().GetDriveId()] = clients[i]
+			}
 		}
-		configuration := &shares.ItemDriveItemRequestBuilderGetRequestConfiguration{
-			QueryParameters: requestParameters,
+	}
+
+	for _, site := range config.Sites {
+		children, err := getItemsForSite(ctx, client, site, report)
+		if err != nil {
+			exitWithError(err)
 		}
-		shareDriveItem, err := client.Shares().BySharedDriveItemId(encodeURL(link)).DriveItem().Get(ctx, configuration)
+		addItems(items, sources, report, config, site.source(), children)
+	}
+
+	for _, drive := range config.Drives {
+		children, err := getItemsForDrivePath(ctx, client, drive)
 		if err != nil {
-			logrus.Error(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
+		addItems(items, sources, report, config, drive.source(), children)
+	}
+
+	for _, list := range config.Lists {
+		children, complete, err := getItemsForList(ctx, client, list, report)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !complete {
+			incomplete[list.source()] = true
+		}
+		addItems(items, sources, report, config, list.source(), children)
+	}
+
+	if config.MyDrive {
+		driveURL, children, err := getItemsForMyDrive(ctx, client)
+		if err != nil {
+			exitWithError(err)
+		}
+		addItems(items, sources, report, config, driveURL, children)
+	}
+
+	if config.Search != "" {
+		children, err := getItemsForSearch(ctx, client, config.Search)
+		if err != nil {
+			exitWithError(err)
+		}
+		addItems(items, sources, report, config, "search:"+config.Search, children)
+	}
+
+	if config.SharedWithMe {
+		sharedItems, skipped, err := getItemsForSharedWithMe(ctx, client, config.SharedWithMeFilter, report)
+		if err != nil {
+			exitWithError(err)
+		}
+		for _, source := range skipped {
+			incomplete[source] = true
+		}
+		sharedURLs := make([]string, 0, len(sharedItems))
+		for sharedURL := range sharedItems {
+			sharedURLs = append(sharedURLs, sharedURL)
+		}
+		slices.Sort(sharedURLs)
+		for _, sharedURL := range sharedURLs {
+			addItems(items, sources, report, config, sharedURL, sharedItems[sharedURL])
+		}
+	}
 
-		children, err := getChildrenFileForItem(ctx, client, shareDriveItem)
+	for _, group := range config.Groups {
+		driveURL, children, err := getItemsForGroup(ctx, client, group)
 		if err != nil {
+			exitWithError(err)
+		}
+		addItems(items, sources, report, config, driveURL, children)
+	}
+
+	for _, channel := range config.Channels {
+		folderURL, children, err := getItemsForChannel(ctx, client, channel)
+		if err != nil {
+			exitWithError(err)
+		}
+		addItems(items, sources, report, config, folderURL, children)
+	}
+
+	if config.AllGroupDrives {
+		groupItems, err := getItemsForGroups(ctx, client, config.GroupFilter)
+		if err != nil {
+			exitWithError(err)
+		}
+		driveURLs := make([]string, 0, len(groupItems))
+		for driveURL := range groupItems {
+			driveURLs = append(driveURLs, driveURL)
+		}
+		slices.Sort(driveURLs)
+		for _, driveURL := range driveURLs {
+			addItems(items, sources, report, config, driveURL, groupItems[driveURL])
+		}
+	}
+
+	if config.Shortcuts == ShortcutsResolve {
+		resolveShortcuts(ctx, client, items, sources, report, config)
+	}
+
+	if isCheckMode() {
+		exitWithCheck(items, metadata, incomplete, config, outputDir)
+	}
+
+	report.countTypes(items)
+
+	syncer := NewSyncer(client, config, outputDir, metadataPath, metadata, report)
+	syncer.driveClients = driveClients
+	syncer.incomplete = incomplete
+	syncer.statusPath = statusPath
+	syncer.cacheDir = os.Getenv("ONEDRIVE_CACHE_DIR")
+	if config.DeletionLog {
+		syncer.deletionsPath = path.Join(dataPath, "deletions.ndjson")
+	}
+	syncErr := syncer.saveToMetadata(ctx, items, sources)
+	if syncErr != nil && !errors.Is(syncErr, errPartialSync) {
+		syncer.progress.finish(StatusFailed)
+		exitWithError(syncErr)
+	}
+
+	if err := syncer.flushMetadata(); err != nil {
+		exitWithError(err)
+	}
+	logrus.Info(fmt.Sprintf("Saved metadata to %s", metadataPath))
+	if err := writeManifests(outputDir, syncer.metadata, config.Checksums); err != nil {
+		exitWithError(err)
+	}
+	// A partial sync would leave files of the previous run in the snapshot of this one.
+	if snapshotDir != "" && syncErr == nil {
+		dir, err := writeSnapshot(snapshotDir, outputDir, syncer.metadata, report.StartedAt)
+		if err != nil {
+			exitWithError(err)
+		}
+		logrus.Info(fmt.Sprintf("Saved snapshot to %s", dir))
+	}
+	syncer.progress.finish(StatusFinished)
+
+	report.finish()
+	if err := writeJSON(reportPath, report); err != nil {
+		exitWithError(err)
+	}
+
+	if config.MarkdownReport {
+		if err := writeMarkdownReport(path.Join(dataPath, "SYNC_REPORT.md"), report, items, syncErr); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	if isToolMode() {
+		if err := printToolResult(newToolResult(report, len(items), syncErr)); err != nil {
 			logrus.Error(err)
 			os.Exit(1)
 		}
-		for _, child := range children {
-			items[*child.GetId()] = child
-		}
 	}
 
-	if err := saveToMetadata(ctx, metadata, client, dataPath, items); err != nil {
-		logrus.Error(err)
+	if syncErr != nil {
+		logrus.Error(syncErr)
 		os.Exit(1)
 	}
+}
 
-	data, err := json.MarshalIndent(metadata, "", "  ")
+func writeJSON(p string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		logrus.Error(err)
-		os.Exit(1)
+		return err
 	}
-	err = os.WriteFile(metadataPath, data, 0644)
-	if err != nil {
-		logrus.Error(err)
-		os.Exit(1)
+	return os.WriteFile(p, data, 0644)
+}
+
+// addItems adds the items found via source that can be synced and records the others in the report.
+// An item already found via an earlier source is kept as is and source is recorded as an alias.
+func addItems(items map[string]models.DriveItemable, sources map[string][]string, report *SyncReport, config Config, source string, children []models.DriveItemable) {
+	for _, child := range children {
+		if reason := config.skipReason(child); reason != "" {
+			report.skip(child, reason)
+			continue
+		}
+		id := *child.GetId()
+		if slices.Contains(sources[id], source) {
+			continue
+		}
+		if _, ok := items[id]; ok {
+			logrus.Info(fmt.Sprintf("%s is also reachable via %s", *child.GetName(), source))
+		} else {
+			items[id] = child
+		}
+		sources[id] = append(sources[id], source)
+		report.Sources[source]++
 	}
-	logrus.Info(fmt.Sprintf("Saved metadata to %s", metadataPath))
 }
 
+// maxDepth limits how many levels of folders below a synced folder are listed, as set by
+// Config.MaxDepth. Zero means no limit.
+var maxDepth int
+
 func getChildrenFileForItem(ctx context.Context, client *msgraphsdk.GraphServiceClient, item models.DriveItemable) ([]models.DriveItemable, error) {
-	if item.GetFile() != nil {
+	return getFilesAtDepth(ctx, client, item, 0)
+}
+
+// getFilesAtDepth returns the files below item, a folder depth levels below the synced folder.
+func getFilesAtDepth(ctx context.Context, client *msgraphsdk.GraphServiceClient, item models.DriveItemable, depth int) ([]models.DriveItemable, error) {
+	if item.GetFolder() == nil {
 		return []models.DriveItemable{item}, nil
 	}
 
-	var result []models.DriveItemable
-	for _, child := range item.GetChildren() {
-		item, err := client.Drives().ByDriveId(*child.GetParentReference().GetDriveId()).Items().ByDriveItemId(*child.GetId()).Get(ctx, &drives2.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration{
-			QueryParameters: &drives2.ItemItemsDriveItemItemRequestBuilderGetQueryParameters{
-				Expand: []string{"children"},
-			},
-		})
-		if err != nil {
-			return nil, err
+	children, err := listChildren(ctx, client, item)
+	if err != nil {
+		return nil, err
+	}
+
+	var result, incomplete []models.DriveItemable
+	for _, child := range children {
+		if isComplete(child) {
+			result = append(result, child)
+		} else if child.GetFolder() != nil && maxDepth > 0 && depth >= maxDepth {
+			logrus.Debug(fmt.Sprintf("Not listing folder %s below maxDepth", deref(child.GetName())))
+		} else {
+			incomplete = append(incomplete, child)
 		}
-		children, err := getChildrenFileForItem(ctx, client, item)
+	}
+
+	fetched, err := getItemsWithChildren(ctx, client, incomplete)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range fetched {
+		files, err := getFilesAtDepth(ctx, client, item, depth+1)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, children...)
+		result = append(result, files...)
 	}
 	return result, nil
 }
 
-func saveToMetadata(ctx context.Context, metadata map[string]FileDetails, client *msgraphsdk.GraphServiceClient, dataPath string, items map[string]models.DriveItemable) error {
-	for _, item := range items {
-		if detail, ok := metadata[*item.GetId()]; ok {
-			if detail.Sync {
-				downloadPath := path.Join(dataPath, *item.GetId(), detail.FileName)
-				if _, err := os.Stat(path.Join(dataPath, *item.GetId())); err != nil {
-					err := os.MkdirAll(path.Join(dataPath, *item.GetId()), 0755)
-					if err != nil {
-						return err
-					}
-				}
-				if _, err := os.Stat(downloadPath); err != nil || detail.UpdatedAt != (*item.GetLastModifiedDateTime()).String() {
-					{
-						data, err := client.Drives().ByDriveId(*item.GetParentReference().GetDriveId()).Items().ByDriveItemId(*item.GetId()).Content().Get(ctx, nil)
-						if err != nil {
-							return err
-						}
-
-						err = os.WriteFile(downloadPath, data, 0644)
-						if err != nil {
-							return err
-						}
-						logrus.Info(fmt.Sprintf("Downloaded %s", downloadPath))
-					}
-				}
-			}
-			detail.DisplayName = getDisplayName(item)
-			detail.FileName = *item.GetName()
-			detail.URL = *item.GetWebUrl()
-			detail.UpdatedAt = (*item.GetLastModifiedDateTime()).String()
-			metadata[*item.GetId()] = detail
-		} else {
-			metadata[*item.GetId()] = FileDetails{
-				FileName:    *item.GetName(),
-				DisplayName: getDisplayName(item),
-				URL:         *item.GetWebUrl(),
-				UpdatedAt:   (*item.GetLastModifiedDateTime()).String(),
-			}
+// expandedChildrenLimit is the most children Graph returns inline when they are expanded.
+const expandedChildrenLimit = 200
+
+// listChildren returns every child of a folder. The expanded children are used as they are unless
+// Graph truncated them, in which case the full collection is paged through.
+func listChildren(ctx context.Context, client *msgraphsdk.GraphServiceClient, item models.DriveItemable) ([]models.DriveItemable, error) {
+	_, truncated := item.GetAdditionalData()["children@odata.nextLink"]
+	if !truncated && len(item.GetChildren()) < expandedChildrenLimit {
+		return item.GetChildren(), nil
+	}
+
+	builder := client.Drives().ByDriveId(*item.GetParentReference().GetDriveId()).Items().ByDriveItemId(*item.GetId()).Children()
+	page, err := withRetry(ctx, graphRetry, func() (models.DriveItemCollectionResponseable, error) {
+		return builder.Get(ctx, &drives2.ItemItemsItemChildrenRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemItemsItemChildrenRequestBuilderGetQueryParameters{
+				Select: driveItemFields,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.DriveItemable
+	for {
+		result = append(result, page.GetValue()...)
+		if page.GetOdataNextLink() == nil {
+			return result, nil
+		}
+		next := *page.GetOdataNextLink()
+		page, err = withRetry(ctx, graphRetry, func() (models.DriveItemCollectionResponseable, error) {
+			return builder.WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
+}
 
-	for id := range metadata {
-		if _, ok := items[id]; !ok {
-			delete(metadata, id)
+// exitWithReport records err as the reason the run failed in the report and exits.
+// The report of the last sync is kept when only checking.
+func exitWithReport(report *SyncReport, reportPath string, code ErrorCode, err error) {
+	report.fail(code, err)
+	report.finish()
+	if !isCheckMode() {
+		if err := writeJSON(reportPath, report); err != nil {
+			logrus.Error(err)
 		}
 	}
-	return nil
+	exitWithError(err)
+}
+
+// isComplete reports whether an expanded child is a file that already carries every property
+// the sync needs, so it can be used as is instead of being fetched again.
+func isComplete(item models.DriveItemable) bool {
+	return item.GetFile() != nil &&
+		item.GetId() != nil &&
+		item.GetName() != nil &&
+		item.GetWebUrl() != nil &&
+		item.GetLastModifiedDateTime() != nil &&
+		item.GetParentReference() != nil &&
+		item.GetParentReference().GetDriveId() != nil &&
+		item.GetParentReference().GetPath() != nil
+}
+
+func updateDetail(detail FileDetails, item models.DriveItemable, timestampFormat string) FileDetails {
+	detail.DisplayName = getDisplayName(item)
+	detail.FileName = *item.GetName()
+	detail.URL = normalizeWebURL(*item.GetWebUrl())
+	detail.UpdatedAt = formatTimestamp(*item.GetLastModifiedDateTime(), timestampFormat)
+	detail.Photo = photoDetails(item)
+	detail.ETag = ""
+	if item.GetETag() != nil {
+		detail.ETag = *item.GetETag()
+	}
+	detail.Description = ""
+	if item.GetDescription() != nil {
+		detail.Description = *item.GetDescription()
+	}
+	return detail
 }
 
 func getDisplayName(item models.DriveItemable) string {
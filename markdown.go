@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// boilerplate holds the elements that never carry document content.
+var boilerplate = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// isHTML reports whether item is a web page, including SharePoint .aspx pages.
+func isHTML(item models.DriveItemable) bool {
+	switch strings.ToLower(path.Ext(*item.GetName())) {
+	case ".html", ".htm", ".aspx":
+		return true
+	}
+	t := item.GetFile().GetMimeType()
+	return t != nil && *t == "text/html"
+}
+
+// htmlToMarkdown converts the content of an HTML document to Markdown, dropping navigation,
+// scripts and other boilerplate.
+func htmlToMarkdown(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	m := &markdownWriter{}
+	m.children(doc)
+	return strings.TrimSpace(blankLines.ReplaceAllString(m.String(), "\n\n")) + "\n", nil
+}
+
+type markdownWriter struct {
+	strings.Builder
+	// lists holds the item counter of each enclosing list, -1 for unordered lists.
+	lists []int
+	pre   bool
+}
+
+func (m *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.node(c)
+	}
+}
+
+func (m *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		m.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		m.children(n)
+		return
+	}
+
+	if boilerplate[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		m.WriteString("\n\n" + strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		m.children(n)
+		m.WriteString("\n\n")
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Table:
+		m.WriteString("\n\n")
+		m.children(n)
+		m.WriteString("\n\n")
+	case atom.Br:
+		m.WriteString("\n")
+	case atom.Hr:
+		m.WriteString("\n\n---\n\n")
+	case atom.Strong, atom.B:
+		m.wrap(n, "**")
+	case atom.Em, atom.I:
+		m.wrap(n, "_")
+	case atom.Code:
+		if m.pre {
+			m.children(n)
+		} else {
+			m.wrap(n, "`")
+		}
+	case atom.Pre:
+		m.WriteString("\n\n```\n")
+		m.pre = true
+		m.children(n)
+		m.pre = false
+		m.WriteString("\n```\n\n")
+	case atom.A:
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			m.children(n)
+			return
+		}
+		m.WriteString("[")
+		m.children(n)
+		m.WriteString("](" + href + ")")
+	case atom.Img:
+		if alt := attr(n, "alt"); alt != "" {
+			m.WriteString("![" + alt + "](" + attr(n, "src") + ")")
+		}
+	case atom.Ul, atom.Ol:
+		counter := -1
+		if n.DataAtom == atom.Ol {
+			counter = 0
+		}
+		m.lists = append(m.lists, counter)
+		m.WriteString("\n")
+		m.children(n)
+		m.lists = m.lists[:len(m.lists)-1]
+		m.WriteString("\n")
+	case atom.Li:
+		m.WriteString("\n" + strings.Repeat("  ", max(len(m.lists)-1, 0)))
+		if len(m.lists) > 0 && m.lists[len(m.lists)-1] >= 0 {
+			m.lists[len(m.lists)-1]++
+			m.WriteString(fmt.Sprintf("%d. ", m.lists[len(m.lists)-1]))
+		} else {
+			m.WriteString("- ")
+		}
+		m.children(n)
+	case atom.Tr:
+		m.WriteString("\n|")
+		m.children(n)
+		if headers := countChildren(n, atom.Th); headers > 0 {
+			m.WriteString("\n|" + strings.Repeat(" --- |", headers))
+		}
+	case atom.Td, atom.Th:
+		m.WriteString(" ")
+		m.children(n)
+		m.WriteString(" |")
+	default:
+		m.children(n)
+	}
+}
+
+func (m *markdownWriter) wrap(n *html.Node, marker string) {
+	m.WriteString(marker)
+	m.children(n)
+	m.WriteString(marker)
+}
+
+func (m *markdownWriter) text(s string) {
+	if m.pre {
+		m.WriteString(s)
+		return
+	}
+	// Collapse whitespace the way a browser renders it.
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			m.WriteString(" ")
+		}
+		return
+	}
+	if s[0] == ' ' || s[0] == '\n' || s[0] == '\t' {
+		m.WriteString(" ")
+	}
+	m.WriteString(strings.Join(fields, " "))
+	if last := s[len(s)-1]; last == ' ' || last == '\n' || last == '\t' {
+		m.WriteString(" ")
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func countChildren(n *html.Node, a atom.Atom) int {
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom == a {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pressureThreshold is the share of the limit above which the heap is considered under pressure.
+const pressureThreshold = 0.9
+
+// pressureFlushInterval is the least time between two flushes caused by memory pressure, so a heap
+// that stays close to the limit does not have metadata written after every file.
+const pressureFlushInterval = 10 * time.Second
+
+// memoryBudget bounds the content held in memory by downloads in progress. Downloads wait for
+// budget before they start, which holds back the workers while large files are being processed.
+// The listed items and the metadata are not part of the budget; they are needed for the whole run.
+type memoryBudget struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	free int64
+	max  int64
+	// limit is the whole limit, of which max is given to downloads.
+	limit int64
+	// relieved is when the last flush caused by memory pressure happened.
+	relieved time.Time
+}
+
+// newMemoryBudget returns a budget of limit bytes, or nil for no limit. Half of the limit is given
+// to downloads, the rest is left for the metadata and everything else. The limit is also set as
+// the soft memory limit of the runtime, so garbage is collected more eagerly as it is approached.
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	debug.SetMemoryLimit(limit)
+	b := &memoryBudget{free: limit / 2, max: limit / 2, limit: limit}
+	b.cond = sync.NewCond(&b.lock)
+	return b
+}
+
+// acquire waits until n bytes are available and returns a function that gives them back. Files
+// larger than the whole budget wait for all of it, so they are downloaded on their own.
+func (b *memoryBudget) acquire(name string, n int64) func() {
+	if b == nil {
+		return func() {}
+	}
+	n = min(n, b.max)
+
+	b.lock.Lock()
+	if b.free < n {
+		logrus.Info(fmt.Sprintf("Waiting for memory to download %s (%s)", name, formatBytes(n)))
+	}
+	for b.free < n {
+		b.cond.Wait()
+	}
+	b.free -= n
+	b.lock.Unlock()
+
+	return func() {
+		b.lock.Lock()
+		b.free += n
+		b.lock.Unlock()
+		b.cond.Broadcast()
+	}
+}
+
+// underPressure reports whether the heap is close to the limit and the state should be flushed,
+// so that the progress is saved should the process be killed and the memory held for the last
+// writes is returned. It reports pressure at most once per pressureFlushInterval.
+func (b *memoryBudget) underPressure() bool {
+	if b == nil {
+		return false
+	}
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 || float64(sample[0].Value.Uint64()) < pressureThreshold*float64(b.limit) {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if time.Since(b.relieved) < pressureFlushInterval {
+		return false
+	}
+	b.relieved = time.Now()
+	logrus.Warn(fmt.Sprintf("Memory use is close to the limit of %s, saving metadata", formatBytes(b.limit)))
+	return true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// metadataFlushItems and metadataFlushInterval bound how much progress is lost when a run is
+	// interrupted: metadata is saved after this many items or this much time, whichever comes first.
+	// Progress is reported in the status file instead, so metadata is kept stable for readers.
+	metadataFlushItems    = 500
+	metadataFlushInterval = 2 * time.Minute
+)
+
+// flushMetadata saves the metadata. It is replaced atomically, so an interrupted run leaves the
+// previous version intact.
+func (s *Syncer) flushMetadata() error {
+	s.lock.Lock()
+	data, err := encodeMetadata(s.metadata, s.config.MetadataFormat)
+	s.unflushed = 0
+	s.flushed = time.Now()
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(s.metadataPath, data)
+}
+
+// replaceFile writes data to p through a temporary file renamed over it, so readers see either
+// the previous or the new content but never a partial write.
+func replaceFile(p string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+"-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
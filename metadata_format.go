@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MetadataCamelCase writes metadata.json with camelCase keys. This is the default.
+	MetadataCamelCase = "camelCase"
+	// MetadataSnakeCase writes metadata.json with snake_case keys.
+	MetadataSnakeCase = "snake_case"
+	// MetadataLegacy writes only the fields of the original metadata.json layout.
+	MetadataLegacy = "legacy"
+)
+
+// legacyFields are the keys of each file in the original metadata.json layout.
+var legacyFields = map[string]bool{
+	"fileName":    true,
+	"displayName": true,
+	"url":         true,
+	"updatedAt":   true,
+	"sync":        true,
+}
+
+// encodeMetadata renders metadata in the given format.
+func encodeMetadata(metadata map[string]FileDetails, format string) ([]byte, error) {
+	if format == "" || format == MetadataCamelCase {
+		return json.MarshalIndent(metadata, "", "  ")
+	}
+
+	files, err := toGeneric(metadata)
+	if err != nil {
+		return nil, err
+	}
+	for id, file := range files {
+		fields := file.(map[string]any)
+		if format == MetadataLegacy {
+			for key := range fields {
+				if !legacyFields[key] {
+					delete(fields, key)
+				}
+			}
+			continue
+		}
+		files[id] = renameKeys(fields, toSnakeCase)
+	}
+	return json.MarshalIndent(files, "", "  ")
+}
+
+// decodeMetadata reads metadata.json written in any format. Snake case keys are mapped back so
+// that switching formats keeps the recorded state.
+func decodeMetadata(data []byte, metadata *map[string]FileDetails) error {
+	var files map[string]any
+	if err := json.Unmarshal(data, &files); err != nil {
+		return err
+	}
+	// The top level keys are item IDs and are left as they are.
+	for id, file := range files {
+		files[id] = renameKeys(file, toCamelCase)
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, metadata)
+}
+
+func toGeneric(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	return result, json.Unmarshal(data, &result)
+}
+
+// userMaps are the keys of objects whose keys are not field names of FileDetails but data, such as
+// SharePoint column names and Graph property names, and must be kept as they are. Their names are
+// the same in every format.
+var userMaps = map[string]bool{
+	"fields":     true,
+	"properties": true,
+}
+
+// renameKeys renames the keys of v and of the objects nested in it, except for userMaps.
+func renameKeys(v any, rename func(string) string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			if userMaps[key] {
+				result[key] = value
+				continue
+			}
+			result[rename(key)] = renameKeys(value, rename)
+		}
+		return result
+	case []any:
+		for i, value := range v {
+			v[i] = renameKeys(value, rename)
+		}
+	}
+	return v
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func toCamelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migrate moves the synced files of the workspace to newOutputDir and points outputDir in
+// config.json at it, so the workspace can be relocated without syncing everything again. Files
+// already moved by hand are left where they are. Files are checked against their recorded hash,
+// and those that are missing or differ are downloaded again by the next sync.
+func migrate(newOutputDir string) error {
+	workspaceDir := os.Getenv("WORKSPACE_DIR")
+	dataPath := path.Join(workspaceDir, "knowledge", "integrations", "onedrive")
+	metadataPath := path.Join(dataPath, "metadata.json")
+	configPath := path.Join(dataPath, "config.json")
+
+	// config.json is updated as a generic object, so that only outputDir changes.
+	rawConfig := map[string]any{}
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &rawConfig); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	var config Config
+	if data, err := json.Marshal(rawConfig); err != nil {
+		return err
+	} else if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	metadata := map[string]FileDetails{}
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return err
+	}
+	if err := decodeMetadata(data, &metadata); err != nil {
+		return err
+	}
+
+	oldDir, err := config.outputPath(workspaceDir, dataPath)
+	if err != nil {
+		return err
+	}
+	newConfig := config
+	newConfig.OutputDir = newOutputDir
+	newDir, err := newConfig.outputPath(workspaceDir, dataPath)
+	if err != nil {
+		return err
+	}
+
+	var moved, missing, changed int
+	for id, detail := range metadata {
+		if !detail.Sync {
+			continue
+		}
+		detail.FilePath = detail.localPath(id)
+		paths := []string{detail.FilePath}
+		for _, derivative := range detail.Derivatives {
+			paths = append(paths, derivative.FilePath)
+		}
+		for _, p := range paths {
+			ok, err := moveFile(oldDir, newDir, p)
+			if err != nil {
+				return err
+			}
+			if ok {
+				moved++
+			}
+		}
+
+		dst, err := safeJoin(newDir, detail.FilePath)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(dst)
+		if errors.Is(err, os.ErrNotExist) {
+			logrus.Warn(fmt.Sprintf("%s is missing and will be downloaded again", dst))
+			missing++
+		} else if err != nil {
+			return err
+		} else if detail.QuickXorHash != "" && quickXorHash(content) != detail.QuickXorHash {
+			logrus.Warn(fmt.Sprintf("%s was modified and will be downloaded again", dst))
+			// Without a recorded version, the file is no longer considered up to date.
+			detail.QuickXorHash, detail.CTag, detail.UpdatedAt = "", "", ""
+			changed++
+		}
+		metadata[id] = detail
+	}
+
+	syncer := NewSyncer(nil, newConfig, newDir, metadataPath, metadata, NewSyncReport())
+	if err := syncer.flushMetadata(); err != nil {
+		return err
+	}
+	rawConfig["outputDir"] = newOutputDir
+	if err := writeJSON(configPath, rawConfig); err != nil {
+		return err
+	}
+	logrus.Info(fmt.Sprintf("Moved %d files to %s, %d missing, %d modified", moved, newDir, missing, changed))
+	return nil
+}
+
+// moveFile moves the file at filePath relative to oldDir to the same path relative to newDir,
+// unless it is not in oldDir. It reports whether the file was moved.
+func moveFile(oldDir, newDir, filePath string) (bool, error) {
+	if oldDir == newDir {
+		return false, nil
+	}
+	src, err := safeJoin(oldDir, filePath)
+	if err != nil {
+		return false, err
+	}
+	dst, err := safeJoin(newDir, filePath)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		// Renaming fails across filesystems, in which case the file is copied instead.
+		if err := copyFile(src, dst); err != nil {
+			return false, err
+		}
+		_, err := removeLocalCopy(oldDir, filePath)
+		return true, err
+	}
+	// Directories left empty in oldDir are removed, like after removeLocalCopy.
+	for dir := filepath.Dir(src); isInside(oldDir, dir); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return true, nil
+}
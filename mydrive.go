@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// getItemsForMyDrive returns every file in the OneDrive of the signed-in user, with the webUrl
+// of the drive to attribute them to.
+func getItemsForMyDrive(ctx context.Context, client *msgraphsdk.GraphServiceClient) (string, []models.DriveItemable, error) {
+	drive, err := withRetry(ctx, graphRetry, func() (models.Driveable, error) {
+		return client.Me().Drive().Get(ctx, nil)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	children, err := getItemsForDrive(ctx, client, *drive.GetId())
+	if err != nil {
+		return "", nil, err
+	}
+	logrus.Info(fmt.Sprintf("Found %d files in your OneDrive", len(children)))
+	return deref(drive.GetWebUrl()), children, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// matchesPatterns evaluates glob patterns against p in order, the last matching pattern deciding:
+// a plain pattern returns true, a pattern prefixed with ! returns false. If no pattern matches,
+// unmatched is returned.
+func matchesPatterns(patterns []string, p string, unmatched bool) bool {
+	result := unmatched
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if matchGlob(strings.TrimPrefix(pattern, "!"), p) {
+			result = !negated
+		}
+	}
+	return result
+}
+
+// matchGlob reports whether the slash-separated path p matches pattern, ignoring case like OneDrive
+// does. Segments are matched with path.Match, and a ** segment matches any number of folders.
+// Patterns starting with / are anchored at the root of the drive, others match at any depth.
+func matchGlob(pattern, p string) bool {
+	patternSegments := strings.Split(strings.ToLower(strings.Trim(pattern, "/")), "/")
+	if !strings.HasPrefix(pattern, "/") && patternSegments[0] != "**" {
+		patternSegments = append([]string{"**"}, patternSegments...)
+	}
+	return matchSegments(patternSegments, strings.Split(strings.ToLower(strings.Trim(p, "/")), "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// filteredByPattern reports whether item is left out by the include and exclude patterns. Items
+// listed without the path of their folder, such as shared items and search results, are matched
+// by their name.
+func (c Config) filteredByPattern(item models.DriveItemable) bool {
+	p := getDisplayName(item)
+	if p == "" {
+		p = deref(item.GetName())
+	}
+	if len(c.IncludePatterns) > 0 && !matchesPatterns(c.IncludePatterns, p, false) {
+		return true
+	}
+	return matchesPatterns(c.ExcludePatterns, p, false)
+}
+
+// validatePatterns reports the first malformed pattern.
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(strings.TrimPrefix(pattern, "!"), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// PhotoDetails holds the photo and image facets of an image file. Photos taken with a camera
+// carry a capture time and camera, screenshots and other images only their dimensions.
+type PhotoDetails struct {
+	TakenAt     *time.Time `json:"takenAt,omitempty"`
+	CameraMake  string     `json:"cameraMake,omitempty"`
+	CameraModel string     `json:"cameraModel,omitempty"`
+	Width       int32      `json:"width,omitempty"`
+	Height      int32      `json:"height,omitempty"`
+}
+
+// photoDetails returns the photo details of item, or nil if it is not an image.
+func photoDetails(item models.DriveItemable) *PhotoDetails {
+	photo, image := item.GetPhoto(), item.GetImage()
+	if photo == nil && image == nil {
+		return nil
+	}
+
+	details := &PhotoDetails{}
+	if photo != nil {
+		details.TakenAt = photo.GetTakenDateTime()
+		if photo.GetCameraMake() != nil {
+			details.CameraMake = *photo.GetCameraMake()
+		}
+		if photo.GetCameraModel() != nil {
+			details.CameraModel = *photo.GetCameraModel()
+		}
+	}
+	if image != nil {
+		if image.GetWidth() != nil {
+			details.Width = *image.GetWidth()
+		}
+		if image.GetHeight() != nil {
+			details.Height = *image.GetHeight()
+		}
+	}
+	return details
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+)
+
+// Profile holds the sign-in settings of one tenant or account, so one binary and one profiles
+// file can serve several of them. Each field corresponds to the environment variable noted next
+// to it, which takes precedence when set.
+type Profile struct {
+	// Auth is one of the Auth* credential kinds. ONEDRIVE_AUTH.
+	Auth string `json:"auth,omitempty"`
+	// TenantID and ClientID select the tenant and app registration. ONEDRIVE_TENANT_ID and ONEDRIVE_CLIENT_ID.
+	TenantID string `json:"tenantId,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+	// ClientSecret is used by client-secret and, if set, refresh-token auth. ONEDRIVE_CLIENT_SECRET.
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// RefreshToken is used by refresh-token auth. ONEDRIVE_REFRESH_TOKEN.
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// Cloud is one of the Cloud* national clouds. ONEDRIVE_CLOUD.
+	Cloud string `json:"cloud,omitempty"`
+	// Defaults are config.json settings used for the tenant unless config.json sets them itself.
+	Defaults json.RawMessage `json:"defaults,omitempty"`
+}
+
+// profileFromEnv returns the profile selected with ONEDRIVE_PROFILE from the file named by
+// ONEDRIVE_PROFILES, overridden by the environment. Without ONEDRIVE_PROFILE, it is the
+// environment alone.
+func profileFromEnv() (Profile, error) {
+	var profile Profile
+	if name := os.Getenv("ONEDRIVE_PROFILE"); name != "" {
+		profiles, err := loadProfiles()
+		if err != nil {
+			return profile, err
+		}
+		var ok bool
+		if profile, ok = profiles[name]; !ok {
+			return profile, fmt.Errorf("no profile %q in %s", name, os.Getenv("ONEDRIVE_PROFILES"))
+		}
+	}
+
+	for name, field := range map[string]*string{
+		"ONEDRIVE_AUTH":          &profile.Auth,
+		"ONEDRIVE_TENANT_ID":     &profile.TenantID,
+		"ONEDRIVE_CLIENT_ID":     &profile.ClientID,
+		"ONEDRIVE_CLIENT_SECRET": &profile.ClientSecret,
+		"ONEDRIVE_REFRESH_TOKEN": &profile.RefreshToken,
+		"ONEDRIVE_CLOUD":         &profile.Cloud,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*field = v
+		}
+	}
+	return profile, nil
+}
+
+// loadProfiles reads the profiles file named by ONEDRIVE_PROFILES, a JSON object mapping profile
+// names to profiles. It holds credentials and should only be readable by the user running the sync.
+func loadProfiles() (map[string]Profile, error) {
+	profilesPath := os.Getenv("ONEDRIVE_PROFILES")
+	if profilesPath == "" {
+		return nil, fmt.Errorf("profiles require ONEDRIVE_PROFILES")
+	}
+	data, err := os.ReadFile(profilesPath)
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[string]Profile{}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", profilesPath, err)
+	}
+	return profiles, nil
+}
+
+// linkClients returns the client to resolve each of links with. Links listed in
+// Config.LinkProfiles use a client signed in with their profile, shared by links with the same
+// profile, and the others use client.
+func linkClients(client *msgraphsdk.GraphServiceClient, config Config, links []string, token string, transport http.RoundTripper, transportConfig TransportConfig) ([]*msgraphsdk.GraphServiceClient, error) {
+	clients := make([]*msgraphsdk.GraphServiceClient, len(links))
+	var profiles map[string]Profile
+	profileClients := map[string]*msgraphsdk.GraphServiceClient{}
+	for i, link := range links {
+		name := config.LinkProfiles[link]
+		if name == "" {
+			clients[i] = client
+			continue
+		}
+		if profileClients[name] == nil {
+			if profiles == nil {
+				var err error
+				if profiles, err = loadProfiles(); err != nil {
+					return nil, err
+				}
+			}
+			profile, ok := profiles[name]
+			if !ok {
+				return nil, fmt.Errorf("no profile %q for %s in %s", name, link, os.Getenv("ONEDRIVE_PROFILES"))
+			}
+			cloud, err := cloudByName(profile.Cloud)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+			cred, err := newCredential(token, downloadClient, profile, cloud)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+			if profileClients[name], err = newGraphClient(cred, transport, transportConfig, cloud); err != nil {
+				return nil, err
+			}
+		}
+		clients[i] = profileClients[name]
+	}
+	return clients, nil
+}
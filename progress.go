@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// progressWindow is the period the throughput is averaged over, so the estimate follows
+	// changes in speed such as throttling instead of the average of the whole run.
+	progressWindow = time.Minute
+	// progressInterval is how often progress is logged.
+	progressInterval = 10 * time.Second
+	// statusInterval is how often the status file is updated.
+	statusInterval = time.Second
+)
+
+type progressSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// progress tracks how many bytes of the synced files have been processed and estimates how long
+// the rest will take. The throughput only counts the bytes actually downloaded, since files that
+// are up to date take next to no time.
+type progress struct {
+	lock  sync.Mutex
+	total int64
+	done  int64
+	// downloaded counts the bytes transferred, the samples record it over time.
+	downloaded int64
+	files      int
+	count      int
+	samples    []progressSample
+	logged     time.Time
+	// statusPath is the status file updated every statusInterval, if set.
+	statusPath string
+	startedAt  time.Time
+	updated    time.Time
+}
+
+func newProgress(items map[string]models.DriveItemable, statusPath string, startedAt time.Time) *progress {
+	p := &progress{
+		files:      len(items),
+		samples:    []progressSample{{at: time.Now()}},
+		logged:     time.Now(),
+		statusPath: statusPath,
+		startedAt:  startedAt,
+	}
+	for _, item := range items {
+		p.total += itemSize(item)
+	}
+	return p
+}
+
+func itemSize(item models.DriveItemable) int64 {
+	if item.GetSize() == nil {
+		return 0
+	}
+	return *item.GetSize()
+}
+
+// add records that item has been processed and logs the progress every progressInterval.
+func (p *progress) add(item models.DriveItemable) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	p.done += itemSize(item)
+	p.count++
+	p.sample(now)
+
+	if now.Sub(p.updated) >= statusInterval {
+		p.updated = now
+		writeStatus(p.statusPath, p.status(StatusSyncing))
+	}
+	if now.Sub(p.logged) < progressInterval {
+		return
+	}
+	p.logged = now
+	rate := p.rate()
+	message := fmt.Sprintf("Synced %d/%d files, %s of %s, downloading at %s/s", p.count, p.files, formatBytes(p.done), formatBytes(p.total), formatBytes(int64(rate)))
+	if rate > 0 {
+		message += fmt.Sprintf(", about %s remaining", p.remaining(rate).Round(time.Second))
+	}
+	logrus.Info(message)
+}
+
+// transferred records that n bytes of content were downloaded.
+func (p *progress) transferred(n int64) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.downloaded += n
+	p.sample(time.Now())
+}
+
+// sample records the bytes downloaded by now, dropping the samples outside progressWindow.
+func (p *progress) sample(now time.Time) {
+	p.samples = append(p.samples, progressSample{at: now, bytes: p.downloaded})
+	for len(p.samples) > 2 && now.Sub(p.samples[1].at) > progressWindow {
+		p.samples = p.samples[1:]
+	}
+}
+
+// finish records the final status of the run in the status file.
+func (p *progress) finish(status string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	writeStatus(p.statusPath, p.status(status))
+}
+
+func (p *progress) status(status string) SyncStatus {
+	result := SyncStatus{
+		Status:    status,
+		StartedAt: p.startedAt,
+		Files:     p.files,
+		FilesDone: p.count,
+		Bytes:     p.total,
+		BytesDone: p.done,
+	}
+	if status == StatusSyncing {
+		if rate := p.rate(); rate > 0 {
+			result.BytesPerSecond = rate
+			result.RemainingSeconds = p.remaining(rate).Seconds()
+		}
+	}
+	return result
+}
+
+// rate returns the download throughput in bytes per second over the last progressWindow.
+func (p *progress) rate() float64 {
+	first, last := p.samples[0], p.samples[len(p.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / elapsed
+}
+
+// remaining estimates the time left as if all the files not processed yet have to be downloaded,
+// which errs on the long side when some of them are up to date.
+func (p *progress) remaining(rate float64) time.Duration {
+	return time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+}
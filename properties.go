@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/kiota-abstractions-go/serialization"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// itemProperties returns the named properties of item as Graph returned them. Properties the
+// item does not have are left out.
+func (s *Syncer) itemProperties(item models.DriveItemable, names []string) map[string]any {
+	if len(names) == 0 {
+		return nil
+	}
+
+	// Properties the SDK models are only available parsed, so the item is serialized back to
+	// JSON to pass every property on the same way.
+	data, err := serialization.SerializeToJson(item)
+	if err != nil {
+		s.report.warn(WarnPropertiesUnreadable, *item.GetName(), fmt.Sprintf("Could not read the properties of %s: %v", *item.GetName(), err))
+		return nil
+	}
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		s.report.warn(WarnPropertiesUnreadable, *item.GetName(), fmt.Sprintf("Could not read the properties of %s: %v", *item.GetName(), err))
+		return nil
+	}
+
+	properties := make(map[string]any, len(names))
+	for _, name := range names {
+		if value, ok := all[name]; ok {
+			properties[name] = value
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return properties
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// removeLocalCopy deletes a downloaded file given its path relative to outputDir, along with
+// any parent directories that are left empty. It reports whether there was a file to delete.
+func removeLocalCopy(outputDir, filePath string) (bool, error) {
+	root, err := filepath.Abs(outputDir)
+	if err != nil {
+		return false, err
+	}
+	p, err := safeJoin(root, filePath)
+	if err != nil {
+		return false, err
+	}
+	if err := os.Remove(p); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	logrus.Info(fmt.Sprintf("Removed %s", p))
+
+	for dir := filepath.Dir(p); isInside(root, dir); dir = filepath.Dir(dir) {
+		// Remove fails on directories that still have entries, which ends the walk up.
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+		logrus.Info(fmt.Sprintf("Removed empty directory %s", dir))
+	}
+	return true, nil
+}
+
+// fromIncompleteSource reports whether a file missing from the listing was found via a source that
+// could only be listed in part, in which case it may still exist and is kept.
+func fromIncompleteSource(detail FileDetails, incomplete map[string]bool) bool {
+	return slices.ContainsFunc(detail.Sources, func(source string) bool { return incomplete[source] })
+}
+
+// safeJoin joins elem onto root as an absolute path and refuses any result that is root itself or lies outside it.
+func safeJoin(root string, elem ...string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(append([]string{absRoot}, elem...)...)
+	if !isInside(absRoot, p) {
+		return "", fmt.Errorf("refusing to use %s: not inside %s", p, absRoot)
+	}
+	return p, nil
+}
+
+// isInside reports whether p lies strictly below root. Both paths must be absolute.
+func isInside(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
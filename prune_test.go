@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name string
+		elem string
+		want string
+		ok   bool
+	}{
+		{name: "file", elem: "id/report.pdf", want: "id/report.pdf", ok: true},
+		{name: "routed file", elem: "pdfs/id/report.pdf", want: "pdfs/id/report.pdf", ok: true},
+		{name: "cleaned inside", elem: "id/../other/report.pdf", want: "other/report.pdf", ok: true},
+		{name: "absolute path stays below root", elem: "/etc/passwd", want: "etc/passwd", ok: true},
+		{name: "parent", elem: "..", ok: false},
+		{name: "traversal", elem: "../outside.txt", ok: false},
+		{name: "nested traversal", elem: "id/../../outside.txt", ok: false},
+		{name: "root itself", elem: ".", ok: false},
+		{name: "empty", elem: "", ok: false},
+		{name: "dot dot prefix in name", elem: "..id/report.pdf", want: "..id/report.pdf", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeJoin(root, tt.elem)
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("safeJoin(%q) = %s, want error", tt.elem, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("safeJoin(%q): %v", tt.elem, err)
+			}
+			if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("safeJoin(%q) = %s, want %s", tt.elem, got, want)
+			}
+		})
+	}
+}
+
+func TestIsInside(t *testing.T) {
+	root := filepath.FromSlash("/workspace/out")
+	tests := []struct {
+		p    string
+		want bool
+	}{
+		{p: "/workspace/out/id/a.pdf", want: true},
+		{p: "/workspace/out", want: false},
+		{p: "/workspace", want: false},
+		{p: "/workspace/outside/a.pdf", want: false},
+		{p: "/mnt/other/out/a.pdf", want: false},
+		{p: "/", want: false},
+	}
+	for _, tt := range tests {
+		if got := isInside(root, filepath.FromSlash(tt.p)); got != tt.want {
+			t.Errorf("isInside(%s, %s) = %v, want %v", root, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail FileDetails
+		want   string
+	}{
+		{name: "recorded path", detail: FileDetails{FileName: "new.pdf", FilePath: "pdfs/id/new.pdf"}, want: "pdfs/id/new.pdf"},
+		{name: "renamed before the path was recorded", detail: FileDetails{FileName: "old.pdf"}, want: "id/old.pdf"},
+	}
+	for _, tt := range tests {
+		if got := tt.detail.localPath("id"); got != tt.want {
+			t.Errorf("%s: localPath = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveLocalCopy(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are created below the output directory before removing filePath.
+		files    []string
+		filePath string
+		// remaining are the paths expected below the output directory afterwards.
+		remaining []string
+		removed   bool
+		wantErr   bool
+	}{
+		{
+			name:      "removes empty parents",
+			files:     []string{"pdfs/id/a.pdf"},
+			filePath:  "pdfs/id/a.pdf",
+			remaining: nil,
+			removed:   true,
+		},
+		{
+			name:      "keeps parents with other files",
+			files:     []string{"id/a.pdf", "id/a.pdf.txt"},
+			filePath:  "id/a.pdf",
+			remaining: []string{"id", "id/a.pdf.txt"},
+			removed:   true,
+		},
+		{
+			name:      "moved item leaves its new location",
+			files:     []string{"old/id/a.pdf", "new/id/a.pdf"},
+			filePath:  "old/id/a.pdf",
+			remaining: []string{"new", "new/id", "new/id/a.pdf"},
+			removed:   true,
+		},
+		{
+			name:      "renamed item keeps the renamed file",
+			files:     []string{"id/old.pdf", "id/new.pdf"},
+			filePath:  "id/old.pdf",
+			remaining: []string{"id", "id/new.pdf"},
+			removed:   true,
+		},
+		{
+			name:      "missing file",
+			filePath:  "id/a.pdf",
+			remaining: nil,
+		},
+		{
+			name:     "traversal",
+			filePath: "../outside.txt",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			outputDir := filepath.Join(parent, "out")
+			if err := os.Mkdir(outputDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			outside := filepath.Join(parent, "outside.txt")
+			if err := os.WriteFile(outside, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.files {
+				p := filepath.Join(outputDir, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := removeLocalCopy(outputDir, tt.filePath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("removeLocalCopy(%q) error = %v, want error %v", tt.filePath, err, tt.wantErr)
+			}
+			if removed != tt.removed {
+				t.Errorf("removeLocalCopy(%q) removed = %v, want %v", tt.filePath, removed, tt.removed)
+			}
+			if _, err := os.Stat(outside); err != nil {
+				t.Errorf("file outside the output directory was touched: %v", err)
+			}
+			if _, err := os.Stat(outputDir); err != nil {
+				t.Fatalf("output directory was removed: %v", err)
+			}
+
+			var remaining []string
+			filepath.WalkDir(outputDir, func(p string, _ os.DirEntry, err error) error {
+				if err == nil && p != outputDir {
+					rel, _ := filepath.Rel(outputDir, p)
+					remaining = append(remaining, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if !slices.Equal(remaining, tt.remaining) {
+				t.Errorf("remaining = %v, want %v", remaining, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestFromIncompleteSource(t *testing.T) {
+	incomplete := map[string]bool{"https://contoso.sharepoint.com/shared": true}
+	tests := []struct {
+		name    string
+		sources []string
+		want    bool
+	}{
+		{name: "incomplete source", sources: []string{"https://contoso.sharepoint.com/shared"}, want: true},
+		{name: "incomplete alias", sources: []string{"https://contoso.sharepoint.com/docs", "https://contoso.sharepoint.com/shared"}, want: true},
+		{name: "listed source", sources: []string{"https://contoso.sharepoint.com/docs"}, want: false},
+		{name: "no sources", sources: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := fromIncompleteSource(FileDetails{Sources: tt.sources}, incomplete); got != tt.want {
+			t.Errorf("%s: fromIncompleteSource = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
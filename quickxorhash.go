@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// errHashMismatch is returned when downloaded content does not match the hash reported by Graph,
+// which means the transfer was corrupted. It is retried like other transient errors.
+var errHashMismatch = errors.New("content does not match its quickXorHash")
+
+// quickXorHash returns the base64 encoded QuickXorHash of data, the hash OneDrive and SharePoint
+// report for every file.
+func quickXorHash(data []byte) string {
+	var h quickXorHasher
+	h.Write(data)
+	return h.String()
+}
+
+// quickXorHasher computes a QuickXorHash incrementally, for content too large to hold in memory.
+// Each byte is XORed into a 160 bit block, shifted by 11 bits more than the previous one, and the
+// length of the content is XORed into the last 64 bits.
+type quickXorHasher struct {
+	block [21]byte
+	n     uint64
+}
+
+func (q *quickXorHasher) Write(p []byte) (int, error) {
+	for _, b := range p {
+		shift := q.n * 11 % 160
+		shifted := uint16(b) << (shift % 8)
+		q.block[shift/8] ^= byte(shifted)
+		q.block[shift/8+1] ^= byte(shifted >> 8)
+		q.n++
+	}
+	return len(p), nil
+}
+
+// String returns the base64 encoded hash of the content written so far.
+func (q *quickXorHasher) String() string {
+	h := q.block
+	// Bits shifted past the end of the block wrap around to the start.
+	h[0] ^= h[20]
+
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], q.n)
+	for i, b := range length {
+		h[12+i] ^= b
+	}
+	return base64.StdEncoding.EncodeToString(h[:20])
+}
+
+// remoteHash returns the QuickXorHash Graph reports for item, if any.
+func remoteHash(item models.DriveItemable) string {
+	file := item.GetFile()
+	if file == nil || file.GetHashes() == nil {
+		return ""
+	}
+	if h := file.GetHashes().GetQuickXorHash(); h != nil {
+		return *h
+	}
+	return ""
+}
+
+// verifyHash checks data against the QuickXorHash of item. Items without a hash are not verified.
+func verifyHash(item models.DriveItemable, data []byte) error {
+	if remoteHash(item) == "" {
+		return nil
+	}
+	return verifyHashString(item, quickXorHash(data))
+}
+
+// verifyHashString checks the QuickXorHash actual computed for the content of item against the
+// one Graph reports.
+func verifyHashString(item models.DriveItemable, actual string) error {
+	if expected := remoteHash(item); expected != "" && actual != expected {
+		return fmt.Errorf("%s: %w: expected %s, got %s", *item.GetName(), errHashMismatch, expected, actual)
+	}
+	return nil
+}
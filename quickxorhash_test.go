@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestRemoteHash(t *testing.T) {
+	withHash := models.NewDriveItem()
+	file := models.NewFile()
+	hashes := models.NewHashes()
+	hash := "AAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	hashes.SetQuickXorHash(&hash)
+	file.SetHashes(hashes)
+	withHash.SetFile(file)
+
+	withoutHashes := models.NewDriveItem()
+	withoutHashes.SetFile(models.NewFile())
+
+	withoutQuickXorHash := models.NewDriveItem()
+	file = models.NewFile()
+	file.SetHashes(models.NewHashes())
+	withoutQuickXorHash.SetFile(file)
+
+	tests := []struct {
+		name string
+		item models.DriveItemable
+		want string
+	}{
+		{name: "file with hash", item: withHash, want: hash},
+		{name: "file without hashes", item: withoutHashes, want: ""},
+		{name: "file without quickXorHash", item: withoutQuickXorHash, want: ""},
+		{name: "folder", item: models.NewDriveItem(), want: ""},
+	}
+	for _, tt := range tests {
+		if got := remoteHash(tt.item); got != tt.want {
+			t.Errorf("%s: remoteHash = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyHashWithoutHashes(t *testing.T) {
+	item := models.NewDriveItem()
+	item.SetFile(models.NewFile())
+	if err := verifyHash(item, []byte("content")); err != nil {
+		t.Errorf("verifyHash of a file without hashes = %v, want nil", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// tokenRefreshMargin is how long before it expires an access token is replaced.
+const tokenRefreshMargin = 5 * time.Minute
+
+// RefreshTokenCredential redeems a refresh token for access tokens and renews them before they
+// expire, so syncs running longer than the lifetime of one access token complete.
+type RefreshTokenCredential struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	lock         sync.Mutex
+	refreshToken string
+	token        azcore.AccessToken
+}
+
+func NewRefreshTokenCredential(tokenURL, clientID, clientSecret, refreshToken string, httpClient *http.Client) *RefreshTokenCredential {
+	return &RefreshTokenCredential{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   httpClient,
+		refreshToken: refreshToken,
+	}
+}
+
+func (c *RefreshTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.token.Token != "" && time.Until(c.token.ExpiresOn) > tokenRefreshMargin {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.clientID},
+		"refresh_token": {c.refreshToken},
+		"scope":         {strings.Join(append(options.Scopes, "offline_access"), " ")},
+	}
+	if c.clientSecret != "" {
+		form.Set("client_secret", c.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("refreshing access token: %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return azcore.AccessToken{}, fmt.Errorf("refreshing access token: %s: %s", body.Error, body.ErrorDescription)
+	}
+
+	// Refresh tokens are rotated on every use. The new one is only kept in memory, so the next run
+	// starts again from the configured one, which stays valid until it expires.
+	if body.RefreshToken != "" {
+		c.refreshToken = body.RefreshToken
+	}
+	c.token = azcore.AccessToken{
+		Token:     body.AccessToken,
+		ExpiresOn: time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}
+	return c.token, nil
+}
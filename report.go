@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// SkipReason explains why an item was left out of the synced files.
+type SkipReason string
+
+const (
+	SkipFilteredByExtension SkipReason = "filtered-by-extension"
+	SkipTooLarge            SkipReason = "too-large"
+	SkipOverQuota           SkipReason = "over-quota"
+	SkipProtected           SkipReason = "protected"
+	SkipMalware             SkipReason = "malware"
+	SkipUnsupported         SkipReason = "unsupported"
+	SkipEmpty               SkipReason = "empty"
+	SkipTooSmall            SkipReason = "too-small"
+	SkipHidden              SkipReason = "hidden"
+	SkipFilteredByAuthor    SkipReason = "filtered-by-author"
+	SkipFilteredByPattern   SkipReason = "filtered-by-pattern"
+	SkipModifiedBefore      SkipReason = "modified-before"
+	SkipShortcut            SkipReason = "shortcut"
+	SkipNotSampled          SkipReason = "not-sampled"
+)
+
+type SkippedFile struct {
+	FileName    string     `json:"fileName"`
+	DisplayName string     `json:"displayName"`
+	URL         string     `json:"url"`
+	Reason      SkipReason `json:"reason"`
+}
+
+// ErrorCode classifies the error that ended a run.
+type ErrorCode string
+
+const (
+	ErrOutputNotWritable    ErrorCode = "output-not-writable"
+	ErrAuthenticationFailed ErrorCode = "authentication-failed"
+	ErrTokenExpired         ErrorCode = "token-expired"
+)
+
+// WarningCode classifies an issue that did not fail the run.
+type WarningCode string
+
+const (
+	WarnDerivativeFailed     WarningCode = "derivative-failed"
+	WarnFolderUnavailable    WarningCode = "folder-unavailable"
+	WarnColumnsUnavailable   WarningCode = "columns-unavailable"
+	WarnPropertiesUnreadable WarningCode = "properties-unreadable"
+	WarnLinkSkipped          WarningCode = "link-skipped"
+	WarnLibraryNotFound      WarningCode = "library-not-found"
+	WarnTokenExpiring        WarningCode = "token-expiring"
+	WarnShortcutUnresolved   WarningCode = "shortcut-unresolved"
+)
+
+// Warning reports an issue with the synced data that did not fail the run, such as a derivative
+// that could not be generated.
+type Warning struct {
+	Code WarningCode `json:"code"`
+	// FileName is the file the warning is about, if any.
+	FileName string `json:"fileName,omitempty"`
+	Message  string `json:"message"`
+}
+
+type ReportError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// TypeStats counts the files of one content type.
+type TypeStats struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// SyncReport describes the outcome of a single run. It is rewritten to report.json after every run.
+type SyncReport struct {
+	lock sync.Mutex
+
+	SkippedFiles map[string]SkippedFile `json:"skippedFiles"`
+	// MimeTypes breaks the files found in the sources down by content type.
+	MimeTypes map[string]TypeStats `json:"mimeTypes"`
+	// Error is set when the run failed before syncing anything.
+	Error *ReportError `json:"error,omitempty"`
+	// Warnings lists the issues that did not fail the run, in the order they occurred.
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// TokenExpiresAt is when the access token expires, if it could be decoded.
+	TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"`
+	// TokenWarning is set when the token is likely to expire before the sync finishes.
+	TokenWarning string `json:"tokenWarning,omitempty"`
+
+	// NewFiles, UpdatedFiles and RemovedFiles list the display names of the files downloaded for the
+	// first time, downloaded again and removed during the run.
+	NewFiles     []string `json:"newFiles,omitempty"`
+	UpdatedFiles []string `json:"updatedFiles,omitempty"`
+	RemovedFiles []string `json:"removedFiles,omitempty"`
+	// Sources counts the files found via each link, site and group drive.
+	Sources map[string]int `json:"sources,omitempty"`
+}
+
+func NewSyncReport() *SyncReport {
+	return &SyncReport{
+		SkippedFiles: map[string]SkippedFile{},
+		MimeTypes:    map[string]TypeStats{},
+		Sources:      map[string]int{},
+		StartedAt:    time.Now(),
+	}
+}
+
+// finish records how long the run took.
+func (r *SyncReport) finish() {
+	r.DurationSeconds = time.Since(r.StartedAt).Seconds()
+}
+
+func (r *SyncReport) fail(code ErrorCode, err error) {
+	r.Error = &ReportError{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
+func (r *SyncReport) countTypes(items map[string]models.DriveItemable) {
+	for _, item := range items {
+		mimeType := "unknown"
+		if t := item.GetFile().GetMimeType(); t != nil {
+			mimeType = *t
+		}
+		stats := r.MimeTypes[mimeType]
+		stats.Files++
+		if item.GetSize() != nil {
+			stats.Bytes += *item.GetSize()
+		}
+		r.MimeTypes[mimeType] = stats
+	}
+
+	mimeTypes := make([]string, 0, len(r.MimeTypes))
+	for mimeType := range r.MimeTypes {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
+	slices.SortFunc(mimeTypes, func(a, b string) int {
+		return r.MimeTypes[b].Files - r.MimeTypes[a].Files
+	})
+	for _, mimeType := range mimeTypes {
+		stats := r.MimeTypes[mimeType]
+		logrus.Info(fmt.Sprintf("%d %s (%s)", stats.Files, mimeType, formatBytes(stats.Bytes)))
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func (r *SyncReport) skip(item models.DriveItemable, reason SkipReason) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	skipped := SkippedFile{
+		DisplayName: getDisplayName(item),
+		Reason:      reason,
+	}
+	if item.GetName() != nil {
+		skipped.FileName = *item.GetName()
+	}
+	if item.GetWebUrl() != nil {
+		skipped.URL = *item.GetWebUrl()
+	}
+	r.SkippedFiles[*item.GetId()] = skipped
+}
+
+// warn logs message and records it as a warning about fileName, which may be empty.
+func (r *SyncReport) warn(code WarningCode, fileName, message string) {
+	logrus.Warn(message)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Warnings = append(r.Warnings, Warning{
+		Code:     code,
+		FileName: fileName,
+		Message:  message,
+	})
+}
+
+// downloaded records a downloaded file as new or updated.
+func (r *SyncReport) downloaded(displayName string, isNew bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if isNew {
+		r.NewFiles = append(r.NewFiles, displayName)
+	} else {
+		r.UpdatedFiles = append(r.UpdatedFiles, displayName)
+	}
+}
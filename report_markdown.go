@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// largestFilesShown is the number of files listed in the largest files section.
+const largestFilesShown = 10
+
+// writeMarkdownReport writes a summary of the run to p, for posting to a pull request, ticket or chat.
+func writeMarkdownReport(p string, report *SyncReport, items map[string]models.DriveItemable, syncErr error) error {
+	var b strings.Builder
+	b.WriteString("# OneDrive sync report\n\n")
+	b.WriteString(fmt.Sprintf("Started %s, took %s.\n", report.StartedAt.Format(time.RFC3339), time.Duration(report.DurationSeconds*float64(time.Second)).Round(time.Second)))
+
+	b.WriteString("\n## Summary\n\n")
+	b.WriteString("| | Files |\n| --- | --- |\n")
+	b.WriteString(fmt.Sprintf("| Found | %d |\n", len(items)))
+	b.WriteString(fmt.Sprintf("| New | %d |\n", len(report.NewFiles)))
+	b.WriteString(fmt.Sprintf("| Updated | %d |\n", len(report.UpdatedFiles)))
+	b.WriteString(fmt.Sprintf("| Removed | %d |\n", len(report.RemovedFiles)))
+	b.WriteString(fmt.Sprintf("| Skipped | %d |\n", len(report.SkippedFiles)))
+
+	if len(report.Sources) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		sources := make([]string, 0, len(report.Sources))
+		for source := range report.Sources {
+			sources = append(sources, source)
+		}
+		slices.Sort(sources)
+		for _, source := range sources {
+			b.WriteString(fmt.Sprintf("- %s: %d files\n", source, report.Sources[source]))
+		}
+	}
+
+	var problems []string
+	if report.Error != nil {
+		problems = append(problems, fmt.Sprintf("%s: %s", report.Error.Code, report.Error.Message))
+	}
+	if syncErr != nil {
+		problems = append(problems, strings.Split(syncErr.Error(), "\n")...)
+	}
+	if len(problems) > 0 {
+		b.WriteString("\n## Errors\n\n")
+		for _, problem := range problems {
+			b.WriteString("- " + problem + "\n")
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		b.WriteString("\n## Warnings\n\n")
+		for _, warning := range report.Warnings {
+			b.WriteString(fmt.Sprintf("- %s: %s\n", warning.Code, warning.Message))
+		}
+	}
+
+	if skipped := skipCounts(report); len(skipped) > 0 {
+		b.WriteString("\n## Skipped files\n\n")
+		for _, reason := range sortedReasons(skipped) {
+			b.WriteString(fmt.Sprintf("- %s: %d\n", reason, skipped[reason]))
+		}
+	}
+
+	if largest := largestFiles(items, largestFilesShown); len(largest) > 0 {
+		b.WriteString("\n## Largest files\n\n")
+		for _, item := range largest {
+			b.WriteString(fmt.Sprintf("- %s (%s)\n", getDisplayName(item), formatBytes(itemSize(item))))
+		}
+	}
+	return os.WriteFile(p, []byte(b.String()), 0644)
+}
+
+func skipCounts(report *SyncReport) map[SkipReason]int {
+	counts := map[SkipReason]int{}
+	for _, skipped := range report.SkippedFiles {
+		counts[skipped.Reason]++
+	}
+	return counts
+}
+
+func sortedReasons(m map[SkipReason]int) []SkipReason {
+	keys := make([]SkipReason, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// largestFiles returns the n largest items, largest first.
+func largestFiles(items map[string]models.DriveItemable, n int) []models.DriveItemable {
+	result := make([]models.DriveItemable, 0, len(items))
+	for _, item := range items {
+		result = append(result, item)
+	}
+	slices.SortFunc(result, func(a, b models.DriveItemable) int {
+		if c := cmp.Compare(itemSize(b), itemSize(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(*a.GetId(), *b.GetId())
+	})
+	return result[:min(n, len(result))]
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// forcesResync reports whether item is listed in Resync, by its ID, its path or the path of a
+// folder it is in.
+func (c Config) forcesResync(item models.DriveItemable) bool {
+	if len(c.Resync) == 0 {
+		return false
+	}
+	// Items at the root of a drive have no leading slash in their display name, so paths are
+	// compared without one.
+	name := strings.TrimPrefix(getDisplayName(item), "/")
+	for _, target := range c.Resync {
+		if target == *item.GetId() {
+			return true
+		}
+		p := strings.Trim(target, "/")
+		if name == "" || p == "" {
+			continue
+		}
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
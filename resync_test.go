@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func newResyncItem(id, parentPath, name string) models.DriveItemable {
+	item := models.NewDriveItem()
+	item.SetId(&id)
+	item.SetName(&name)
+	parent := models.NewItemReference()
+	parent.SetPath(&parentPath)
+	item.SetParentReference(parent)
+	return item
+}
+
+func TestForcesResync(t *testing.T) {
+	root := newResyncItem("root-id", "/drive/root:", "plan.docx")
+	nested := newResyncItem("nested-id", "/drive/root:/Projects/Docs", "plan.docx")
+
+	tests := []struct {
+		name   string
+		item   models.DriveItemable
+		resync []string
+		want   bool
+	}{
+		{name: "by ID", item: nested, resync: []string{"nested-id"}, want: true},
+		{name: "by path", item: nested, resync: []string{"/Projects/Docs/plan.docx"}, want: true},
+		{name: "by folder", item: nested, resync: []string{"/Projects/"}, want: true},
+		{name: "folder name prefix", item: nested, resync: []string{"/Proj"}, want: false},
+		{name: "root item by path", item: root, resync: []string{"/plan.docx"}, want: true},
+		{name: "root item without leading slash", item: root, resync: []string{"plan.docx"}, want: true},
+		{name: "drive root", item: root, resync: []string{"/"}, want: false},
+		{name: "other file", item: root, resync: []string{"/other.docx"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := (Config{Resync: tt.resync}).forcesResync(tt.item); got != tt.want {
+			t.Errorf("%s: forcesResync(%v) = %v, want %v", tt.name, tt.resync, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/sirupsen/logrus"
+)
+
+// deferredRetryDelay is how long to wait before retrying the items that failed during the main pass.
+const deferredRetryDelay = 30 * time.Second
+
+// RetryPolicy controls how often and how patiently a call that failed with a transient error is retried.
+type RetryPolicy struct {
+	MaxRetries int
+	// Delay is the wait before the first retry. It doubles with every further attempt, up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+var (
+	// graphRetry applies to Graph API calls such as resolving links and listing folders.
+	graphRetry = RetryPolicy{MaxRetries: 3, Delay: time.Second, MaxDelay: 30 * time.Second}
+	// contentRetry applies to file downloads. These are served from a content CDN that drops
+	// connections more often than the API but is rarely throttled for long.
+	contentRetry = RetryPolicy{MaxRetries: 5, Delay: 2 * time.Second, MaxDelay: time.Minute}
+)
+
+// RetryConfig overrides a RetryPolicy from config.json. Unset fields keep the default.
+type RetryConfig struct {
+	MaxRetries      *int `json:"maxRetries,omitempty"`
+	DelaySeconds    int  `json:"delaySeconds,omitempty"`
+	MaxDelaySeconds int  `json:"maxDelaySeconds,omitempty"`
+}
+
+// apply returns policy with the settings of c.
+func (c *RetryConfig) apply(policy RetryPolicy) RetryPolicy {
+	if c == nil {
+		return policy
+	}
+	if c.MaxRetries != nil {
+		policy.MaxRetries = *c.MaxRetries
+	}
+	if c.DelaySeconds > 0 {
+		policy.Delay = time.Duration(c.DelaySeconds) * time.Second
+	}
+	if c.MaxDelaySeconds > 0 {
+		policy.MaxDelay = time.Duration(c.MaxDelaySeconds) * time.Second
+	}
+	return policy
+}
+
+// withRetry runs call, retrying transient failures according to policy. The wait before a retry
+// is the Retry-After the server asked for, or else the backoff delay with up to 50% jitter added
+// so that parallel downloads throttled together do not retry in lockstep.
+func withRetry[T any](ctx context.Context, policy RetryPolicy, call func() (T, error)) (T, error) {
+	delay := policy.Delay
+	for attempt := 0; ; attempt++ {
+		result, err := call()
+		if err == nil || attempt >= policy.MaxRetries || !isRetriable(err) {
+			return result, err
+		}
+		wait, ok := retryAfter(err)
+		if !ok {
+			wait = delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		}
+		logrus.Warn(fmt.Sprintf("Retrying in %s after: %v", wait.Round(time.Millisecond), err))
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, policy.MaxDelay)
+	}
+}
+
+// retryAfter returns the wait requested by the Retry-After header of a failed response, given
+// either in seconds or as an HTTP date.
+func retryAfter(err error) (time.Duration, bool) {
+	var headers *abstractions.ResponseHeaders
+	var odataErr *odataerrors.ODataError
+	var apiErr *abstractions.ApiError
+	if errors.As(err, &odataErr) {
+		headers = odataErr.ResponseHeaders
+	} else if errors.As(err, &apiErr) {
+		headers = apiErr.ResponseHeaders
+	}
+	if headers == nil {
+		return 0, false
+	}
+	values := headers.Get("Retry-After")
+	if len(values) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(values[0])); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(values[0]); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// errPartialSync is returned when some files still could not be downloaded after retrying.
+// The metadata of everything else is up to date and should still be saved.
+var errPartialSync = errors.New("some files could not be synced")
+
+// retryDeferred gives every deferred item one more download attempt and updates its metadata on success.
+// It returns the errors of the items that failed again.
+func (s *Syncer) retryDeferred(ctx context.Context, deferred []models.DriveItemable) []error {
+	if len(deferred) == 0 {
+		return nil
+	}
+
+	logrus.Info(fmt.Sprintf("Retrying %d deferred files in %s", len(deferred), deferredRetryDelay))
+	select {
+	case <-ctx.Done():
+		return []error{ctx.Err()}
+	case <-time.After(deferredRetryDelay):
+	}
+
+	var failed []error
+	for _, item := range deferred {
+		detail, err := s.downloadItem(ctx, item, s.metadata[*item.GetId()])
+		if err != nil {
+			if !s.skipFailed(item, err) {
+				failed = append(failed, fmt.Errorf("%s: %w", *item.GetName(), err))
+			}
+			continue
+		}
+		if err := s.recordItem(ctx, item, detail); err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", *item.GetName(), err))
+		}
+	}
+	return failed
+}
+
+// isRetriable reports whether err is a transient failure such as throttling, a locked file, a dropped
+// connection or a corrupted transfer.
+func isRetriable(err error) bool {
+	switch statusCode(err) {
+	case http.StatusTooManyRequests, http.StatusLocked, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, errHashMismatch)
+}
+
+// isSystemic reports whether err affects the whole run rather than one file, such as rejected
+// credentials or a lost network connection, so the other files would fail the same way.
+func isSystemic(err error) bool {
+	var netErr net.Error
+	var credentialErr *azidentity.AuthenticationFailedError
+	return statusCode(err) == http.StatusUnauthorized || errors.As(err, &credentialErr) || errors.As(err, &netErr)
+}
+
+func statusCode(err error) int {
+	var odataErr *odataerrors.ODataError
+	if errors.As(err, &odataErr) {
+		return odataErr.ResponseStatusCode
+	}
+	var apiErr *abstractions.ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.ResponseStatusCode
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// Route sends the files matching any of its MIME types or extensions to a subdirectory of the
+// output directory.
+type Route struct {
+	// MimeTypes to match, e.g. ["application/pdf"]. A trailing "/*" matches every subtype, e.g. "image/*".
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+	// Extensions to match, e.g. ["xlsx", "csv"]. Like Config.Extensions, they are compared ignoring
+	// case and with or without the leading dot.
+	Extensions []string `json:"extensions,omitempty"`
+	// Dir is the subdirectory, relative to the output directory, e.g. "spreadsheets".
+	Dir string `json:"dir"`
+}
+
+func (r Route) matches(item models.DriveItemable) bool {
+	if hasExtension(r.Extensions, fileExtension(*item.GetName())) {
+		return true
+	}
+	if item.GetFile() == nil {
+		return false
+	}
+	t := item.GetFile().GetMimeType()
+	if t == nil {
+		return false
+	}
+	return slices.ContainsFunc(r.MimeTypes, func(m string) bool {
+		if prefix, ok := strings.CutSuffix(m, "/*"); ok {
+			return strings.HasPrefix(*t, prefix+"/")
+		}
+		return strings.EqualFold(m, *t)
+	})
+}
+
+func (r Route) validate() error {
+	if !filepath.IsLocal(r.Dir) {
+		return fmt.Errorf("invalid route dir %q, must be a relative path inside the output directory", r.Dir)
+	}
+	return nil
+}
+
+// routeDir returns the subdirectory item is written to, the first matching route winning.
+// Files no route matches are written to the output directory itself.
+func (c Config) routeDir(item models.DriveItemable) string {
+	for _, route := range c.Routes {
+		if route.matches(item) {
+			return route.Dir
+		}
+	}
+	return ""
+}
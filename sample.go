@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// SampleConfig limits a run to a sample of the files that would be downloaded, to preview how
+// a large library is ingested. The sample is taken in order of path, so it is the same on every
+// run. Files outside it are recorded in the metadata but not downloaded. Zero means no limit.
+type SampleConfig struct {
+	// Files is the most files downloaded.
+	Files int `json:"files,omitempty"`
+	// PerFolder is the most files downloaded from each folder.
+	PerFolder int `json:"perFolder,omitempty"`
+	// MaxBytes is the most bytes downloaded in total. Larger files are passed over for smaller
+	// ones further on.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+// sample returns the IDs of the items in the sample among those that would be downloaded, as
+// decided by synced.
+func (c SampleConfig) sample(items map[string]models.DriveItemable, synced func(models.DriveItemable) bool) map[string]bool {
+	var candidates []models.DriveItemable
+	for _, item := range items {
+		if synced(item) {
+			candidates = append(candidates, item)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b models.DriveItemable) int {
+		if c := strings.Compare(getDisplayName(a), getDisplayName(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(*a.GetId(), *b.GetId())
+	})
+
+	result := map[string]bool{}
+	perFolder := map[string]int{}
+	var bytes int64
+	for _, item := range candidates {
+		if c.Files > 0 && len(result) >= c.Files {
+			break
+		}
+		folder := path.Dir(getDisplayName(item))
+		if c.PerFolder > 0 && perFolder[folder] >= c.PerFolder {
+			continue
+		}
+		if c.MaxBytes > 0 && bytes+itemSize(item) > c.MaxBytes {
+			continue
+		}
+		result[*item.GetId()] = true
+		perFolder[folder]++
+		bytes += itemSize(item)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// getItemsForSearch returns the files in the OneDrive of the signed-in user, and those shared
+// with them, that match query. Folders that match are synced with everything in them.
+func getItemsForSearch(ctx context.Context, client *msgraphsdk.GraphServiceClient, query string) ([]models.DriveItemable, error) {
+	drive, err := withRetry(ctx, graphRetry, func() (models.Driveable, error) {
+		return client.Me().Drive().Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	builder := client.Drives().ByDriveId(*drive.GetId()).SearchWithQ(&query)
+	found, err := withRetry(ctx, graphRetry, func() (drives2.ItemSearchWithQGetResponseable, error) {
+		return builder.GetAsSearchWithQGetResponse(ctx, &drives2.ItemSearchWithQRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemSearchWithQRequestBuilderGetQueryParameters{
+				// remoteItem tells the items shared with the user apart, see below.
+				Select: append(slices.Clip(driveItemFields), "remoteItem"),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result, incomplete []models.DriveItemable
+	for {
+		for _, item := range found.GetValue() {
+			// Items shared with the user are found as references to the drive they live in.
+			// They are fetched from there like any other incomplete item.
+			if remote := item.GetRemoteItem(); remote != nil {
+				item = models.NewDriveItem()
+				item.SetId(remote.GetId())
+				item.SetParentReference(remote.GetParentReference())
+			}
+			if item.GetId() == nil || item.GetParentReference() == nil || item.GetParentReference().GetDriveId() == nil {
+				continue
+			}
+			if item.GetFolder() == nil && isComplete(item) {
+				result = append(result, item)
+			} else {
+				incomplete = append(incomplete, item)
+			}
+		}
+		if found.GetOdataNextLink() == nil {
+			break
+		}
+		next := *found.GetOdataNextLink()
+		found, err = withRetry(ctx, graphRetry, func() (drives2.ItemSearchWithQGetResponseable, error) {
+			return builder.WithUrl(next).GetAsSearchWithQGetResponse(ctx, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fetched, err := getItemsWithChildren(ctx, client, incomplete)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range fetched {
+		files, err := getChildrenFileForItem(ctx, client, item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, files...)
+	}
+	logrus.Info(fmt.Sprintf("Found %d files matching %q", len(result), query))
+	return result, nil
+}
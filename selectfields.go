@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// driveItemFields are the properties of drive items the sync uses. Selecting only these keeps
+// the responses for large folders small.
+var driveItemFields = []string{
+	"id", "name", "size", "webUrl", "description", "eTag", "cTag",
+	"createdBy", "lastModifiedBy", "lastModifiedDateTime", "parentReference",
+	"file", "folder", "package", "malware", "photo", "image", "audio", "video",
+	downloadURLKey,
+}
+
+// expandChildren expands the children of a folder with the same properties as the folder itself.
+var expandChildren = "children($select=" + strings.Join(driveItemFields, ",") + ")"
+
+// selectItemProperties adds properties to those selected for drive items.
+func selectItemProperties(properties []string) {
+	for _, property := range properties {
+		if !slices.Contains(driveItemFields, property) {
+			driveItemFields = append(driveItemFields, property)
+		}
+	}
+	expandChildren = "children($select=" + strings.Join(driveItemFields, ",") + ")"
+}
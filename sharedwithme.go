@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// getItemsForSharedWithMe returns the files shared with the signed-in user, by the webUrl of
+// the shared file or folder, limited to those whose name contains filter if it is set. Shared
+// items that cannot be accessed are skipped with a warning and their sources returned as
+// incomplete, so the files previously synced from them are kept.
+func getItemsForSharedWithMe(ctx context.Context, client *msgraphsdk.GraphServiceClient, filter string, report *SyncReport) (map[string][]models.DriveItemable, []string, error) {
+	drive, err := withRetry(ctx, graphRetry, func() (models.Driveable, error) {
+		return client.Me().Drive().Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	builder := client.Drives().ByDriveId(*drive.GetId()).SharedWithMe()
+	shared, err := withRetry(ctx, graphRetry, func() (drives2.ItemSharedWithMeGetResponseable, error) {
+		return builder.GetAsSharedWithMeGetResponse(ctx, nil)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := map[string][]models.DriveItemable{}
+	var incomplete []string
+	for {
+		for _, item := range shared.GetValue() {
+			name := deref(item.GetName())
+			if filter != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(filter)) {
+				continue
+			}
+			// The listing only holds a reference to the shared item in the drive it lives in.
+			remote := item.GetRemoteItem()
+			if remote == nil || remote.GetId() == nil || remote.GetParentReference() == nil || remote.GetParentReference().GetDriveId() == nil {
+				continue
+			}
+			// Items are identified by their ID where the listing has no webUrl for them.
+			source := deref(item.GetWebUrl())
+			if source == "" {
+				source = "sharedWithMe:" + *remote.GetId()
+			}
+			children, err := getItemsForRemoteItem(ctx, client, *remote.GetParentReference().GetDriveId(), *remote.GetId())
+			if err != nil {
+				report.warn(WarnLinkSkipped, name, fmt.Sprintf("Skipping %s shared with you: %v", name, err))
+				incomplete = append(incomplete, source)
+				continue
+			}
+			logrus.Info(fmt.Sprintf("Found %d files in %s shared with you", len(children), name))
+			result[source] = children
+		}
+		if shared.GetOdataNextLink() == nil {
+			return result, incomplete, nil
+		}
+		next := *shared.GetOdataNextLink()
+		shared, err = withRetry(ctx, graphRetry, func() (drives2.ItemSharedWithMeGetResponseable, error) {
+			return builder.WithUrl(next).GetAsSharedWithMeGetResponse(ctx, nil)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// getItemsForRemoteItem returns the files of the item with itemID in the drive with driveID.
+func getItemsForRemoteItem(ctx context.Context, client *msgraphsdk.GraphServiceClient, driveID, itemID string) ([]models.DriveItemable, error) {
+	item, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return client.Drives().ByDriveId(driveID).Items().ByDriveItemId(itemID).Get(ctx, &drives2.ItemItemsDriveItemItemRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemItemsDriveItemItemRequestBuilderGetQueryParameters{
+				Select: driveItemFields,
+				Expand: []string{expandChildren},
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getChildrenFileForItem(ctx, client, item)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+const (
+	// ShortcutsKeep syncs .url shortcuts as the small files they are. This is the default.
+	ShortcutsKeep = "keep"
+	// ShortcutsSkip leaves .url shortcuts out of the sync.
+	ShortcutsSkip = "skip"
+	// ShortcutsResolve syncs the documents .url shortcuts point to instead of the shortcuts, when
+	// they can be reached with the current credentials. Other shortcuts are kept.
+	ShortcutsResolve = "resolve"
+)
+
+// isShortcut reports whether item is an Internet shortcut, as created by saving a link to OneDrive.
+func isShortcut(item models.DriveItemable) bool {
+	return strings.HasSuffix(strings.ToLower(*item.GetName()), ".url")
+}
+
+// resolveShortcuts replaces the .url shortcuts among items with the files they point to, found
+// via the same sources. Shortcuts that cannot be resolved are kept with a warning.
+func resolveShortcuts(ctx context.Context, client *msgraphsdk.GraphServiceClient, items map[string]models.DriveItemable, sources map[string][]string, report *SyncReport, config Config) {
+	var shortcuts []models.DriveItemable
+	for _, item := range items {
+		if isShortcut(item) {
+			shortcuts = append(shortcuts, item)
+		}
+	}
+
+	for _, shortcut := range shortcuts {
+		id := *shortcut.GetId()
+		targets, err := shortcutTargets(ctx, client, shortcut)
+		if err != nil {
+			report.warn(WarnShortcutUnresolved, *shortcut.GetName(), fmt.Sprintf("Keeping shortcut %s: %v", getDisplayName(shortcut), err))
+			continue
+		}
+		shortcutSources := sources[id]
+		delete(items, id)
+		delete(sources, id)
+		for _, source := range shortcutSources {
+			report.Sources[source]--
+			addItems(items, sources, report, config, source, targets)
+		}
+	}
+}
+
+// shortcutTargets returns the files the shortcut points to.
+func shortcutTargets(ctx context.Context, client *msgraphsdk.GraphServiceClient, shortcut models.DriveItemable) ([]models.DriveItemable, error) {
+	downloadURL, ok := shortcut.GetAdditionalData()[downloadURLKey].(*string)
+	if !ok || downloadURL == nil {
+		return nil, fmt.Errorf("no download url")
+	}
+	content, err := withRetry(ctx, contentRetry, func() ([]byte, error) {
+		return getContent(ctx, *downloadURL, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	target := shortcutURL(content)
+	if target == "" {
+		return nil, fmt.Errorf("no URL in shortcut")
+	}
+	return getItemsForLink(ctx, client, target)
+}
+
+// shortcutURL returns the target of an Internet shortcut, given in the URL entry of its
+// [InternetShortcut] section, if it is a web address.
+func shortcutURL(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || !strings.EqualFold(key, "URL") {
+			continue
+		}
+		if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+			return value
+		}
+	}
+	return ""
+}
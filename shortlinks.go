@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// maxLinkRedirects limits how many redirects are followed to resolve a shortened link.
+const maxLinkRedirects = 10
+
+// shortLinkHosts are hosts of shortened sharing links, which the shares API does not accept.
+var shortLinkHosts = []string{"1drv.ms"}
+
+// loginHosts are where redirects end up once a link needs signing in, past the sharing URL.
+var loginHosts = []string{"login.microsoftonline.com", "login.live.com", "login.microsoftonline.us", "login.partner.microsoftonline.cn"}
+
+// isShortLink reports whether link has to be resolved before it is passed to the shares API:
+// 1drv.ms links and SharePoint links of the form /:w:/r/..., which redirect to the document.
+func isShortLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if slices.Contains(shortLinkHosts, strings.ToLower(u.Hostname())) {
+		return true
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return len(segments) > 1 && strings.HasPrefix(segments[0], ":") && segments[1] == "r"
+}
+
+// resolveSharingURL follows the redirects of a shortened link to the canonical sharing URL.
+// Other links are returned as they are. The redirects are followed until they leave for a sign-in
+// page, so links that need signing in resolve without credentials.
+func resolveSharingURL(ctx context.Context, link string) (string, error) {
+	if !isShortLink(link) {
+		return link, nil
+	}
+
+	client := &http.Client{
+		Transport: downloadClient.Transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	current := link
+	for i := 0; i < maxLinkRedirects; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", link, err)
+		}
+		resp.Body.Close()
+		location, err := resp.Location()
+		if err != nil {
+			// Not a redirect, so current is as far as the link goes.
+			return current, nil
+		}
+		if slices.Contains(loginHosts, strings.ToLower(location.Hostname())) {
+			return current, nil
+		}
+		current = location.String()
+		if !isShortLink(current) {
+			return current, nil
+		}
+	}
+	return "", fmt.Errorf("failed to resolve %s: more than %d redirects", link, maxLinkRedirects)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	drives2 "github.com/microsoftgraph/msgraph-sdk-go/drives"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+func getItemsForSite(ctx context.Context, client *msgraphsdk.GraphServiceClient, source SiteSource, report *SyncReport) ([]models.DriveItemable, error) {
+	key := source.ID
+	if key == "" {
+		var err error
+		if key, err = siteKey(source.URL); err != nil {
+			return nil, err
+		}
+	}
+	site, err := withRetry(ctx, graphRetry, func() (models.Siteable, error) {
+		return client.Sites().BySiteId(key).Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matched := map[string]bool{}
+	result, err := getItemsForSiteID(ctx, client, *site.GetId(), source, 0, matched)
+	if err != nil {
+		return nil, err
+	}
+	for _, library := range source.Libraries {
+		if !matched[strings.ToLower(library)] {
+			report.warn(WarnLibraryNotFound, "", fmt.Sprintf("No document library named %q found on site %s", library, source.source()))
+		}
+	}
+	return result, nil
+}
+
+func getItemsForSiteID(ctx context.Context, client *msgraphsdk.GraphServiceClient, siteID string, source SiteSource, depth int, matched map[string]bool) ([]models.DriveItemable, error) {
+	var result []models.DriveItemable
+
+	drives, err := listSiteDrives(ctx, client, siteID)
+	if err != nil {
+		return nil, err
+	}
+	for _, drive := range drives {
+		if !libraryMatches(source.Libraries, *drive.GetName()) {
+			continue
+		}
+		matched[strings.ToLower(*drive.GetName())] = true
+		children, err := getItemsForDrive(ctx, client, *drive.GetId())
+		if err != nil {
+			return nil, err
+		}
+		logrus.Info(fmt.Sprintf("Found %d files in library %s", len(children), *drive.GetName()))
+		result = append(result, children...)
+	}
+
+	if !source.IncludeSubsites || (source.MaxSubsiteDepth > 0 && depth >= source.MaxSubsiteDepth) {
+		return result, nil
+	}
+
+	subsites, err := withRetry(ctx, graphRetry, func() (models.SiteCollectionResponseable, error) {
+		return client.Sites().BySiteId(siteID).Sites().Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, subsite := range subsites.GetValue() {
+			children, err := getItemsForSiteID(ctx, client, *subsite.GetId(), source, depth+1, matched)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, children...)
+		}
+		if subsites.GetOdataNextLink() == nil {
+			break
+		}
+		next := *subsites.GetOdataNextLink()
+		subsites, err = withRetry(ctx, graphRetry, func() (models.SiteCollectionResponseable, error) {
+			return client.Sites().BySiteId(siteID).Sites().WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// listSiteDrives returns the document libraries of a site.
+func listSiteDrives(ctx context.Context, client *msgraphsdk.GraphServiceClient, siteID string) ([]models.Driveable, error) {
+	drives, err := withRetry(ctx, graphRetry, func() (models.DriveCollectionResponseable, error) {
+		return client.Sites().BySiteId(siteID).Drives().Get(ctx, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Driveable
+	for {
+		result = append(result, drives.GetValue()...)
+		if drives.GetOdataNextLink() == nil {
+			return result, nil
+		}
+		next := *drives.GetOdataNextLink()
+		drives, err = withRetry(ctx, graphRetry, func() (models.DriveCollectionResponseable, error) {
+			return client.Sites().BySiteId(siteID).Drives().WithUrl(next).Get(ctx, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getItemsForDrive returns every file in a drive.
+func getItemsForDrive(ctx context.Context, client *msgraphsdk.GraphServiceClient, driveID string) ([]models.DriveItemable, error) {
+	root, err := withRetry(ctx, graphRetry, func() (models.DriveItemable, error) {
+		return client.Drives().ByDriveId(driveID).Root().Get(ctx, &drives2.ItemRootRequestBuilderGetRequestConfiguration{
+			QueryParameters: &drives2.ItemRootRequestBuilderGetQueryParameters{
+				Select: driveItemFields,
+				Expand: []string{expandChildren},
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getChildrenFileForItem(ctx, client, root)
+}
+
+// libraryMatches reports whether a document library should be synced. Library names are matched
+// case-insensitively, the same way SharePoint treats them.
+func libraryMatches(libraries []string, name string) bool {
+	if len(libraries) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(libraries, func(library string) bool {
+		return strings.EqualFold(library, name)
+	})
+}
+
+// siteKey turns a site URL into the hostname:/server-relative-path: form accepted by /sites/{site-id}.
+func siteKey(siteURL string) (string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid site url %q", siteURL)
+	}
+	p := strings.Trim(u.Path, "/")
+	if p == "" {
+		return u.Host, nil
+	}
+	return fmt.Sprintf("%s:/%s:", u.Host, p), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotLayout names the directory of each snapshot after the start of its run, in UTC, so
+// the snapshots sort chronologically.
+const snapshotLayout = "20060102T150405Z"
+
+// snapshotPath returns the absolute directory snapshots are written to, or an empty path if
+// snapshots are disabled.
+func (c Config) snapshotPath(workspaceDir string) (string, error) {
+	if c.SnapshotDir == "" {
+		return "", nil
+	}
+	workspace, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return "", err
+	}
+	dir := c.SnapshotDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workspace, dir)
+	}
+	dir = filepath.Clean(dir)
+	if !c.AllowExternalOutput && !isInside(workspace, dir) {
+		return "", fmt.Errorf("snapshotDir %s is outside the workspace %s, set allowExternalOutput to use it", c.SnapshotDir, workspace)
+	}
+	return dir, nil
+}
+
+// writeSnapshot records the synced files of a run in a new directory below snapshotDir, with the
+// same layout as outputDir and a copy of the metadata, and returns the directory. Files are hard
+// linked from outputDir: synced files are always replaced rather than written in place, so a
+// snapshot keeps the content of its run, and files unchanged between runs share their storage
+// across snapshots. Files are copied where hard links are not possible.
+func writeSnapshot(snapshotDir, outputDir string, metadata map[string]FileDetails, startedAt time.Time) (string, error) {
+	dir := filepath.Join(snapshotDir, startedAt.UTC().Format(snapshotLayout))
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("snapshot %s already exists", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	for id, detail := range metadata {
+		if !detail.Sync {
+			continue
+		}
+		paths := []string{detail.localPath(id)}
+		for _, derivative := range detail.Derivatives {
+			paths = append(paths, derivative.FilePath)
+		}
+		for _, p := range paths {
+			if err := snapshotFile(outputDir, dir, p); err != nil {
+				return "", err
+			}
+		}
+	}
+	return dir, writeJSON(filepath.Join(dir, "metadata.json"), metadata)
+}
+
+// snapshotFile links, or copies, filePath in outputDir to the same path in dir. Files missing
+// from outputDir, such as those that failed to download, are left out.
+func snapshotFile(outputDir, dir, filePath string) error {
+	src, err := safeJoin(outputDir, filePath)
+	if err != nil {
+		return err
+	}
+	dst, err := safeJoin(dir, filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0444)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// stateVersion is the version of the State format, increased on incompatible changes.
+const stateVersion = 1
+
+// State is the sync state of a workspace as exported by the export-state command, for backing it
+// up or inspecting it from other systems. Files always uses the camelCase keys of FileDetails,
+// whatever metadataFormat is configured. The sync does not keep delta tokens, so there are none
+// to export.
+type State struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// Files maps drive item IDs to what is known about them, as in metadata.json.
+	Files map[string]FileDetails `json:"files"`
+	// ExternalLinks are the shared links of the workspace, as in externalLinks.json.
+	ExternalLinks map[string]string `json:"externalLinks"`
+	// Deletions are the deleted files not yet consumed from deletions.ndjson, if deletionLog is set.
+	Deletions []Deletion `json:"deletions,omitempty"`
+}
+
+// exportState writes the state of the workspace to p, or to stdout if p is empty.
+func exportState(p string) error {
+	dataPath := path.Join(os.Getenv("WORKSPACE_DIR"), "knowledge", "integrations", "onedrive")
+	state := State{
+		Version:       stateVersion,
+		ExportedAt:    time.Now().UTC(),
+		Files:         map[string]FileDetails{},
+		ExternalLinks: map[string]string{},
+	}
+	if data, err := os.ReadFile(path.Join(dataPath, "metadata.json")); err == nil {
+		if err := decodeMetadata(data, &state.Files); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if data, err := os.ReadFile(path.Join(dataPath, "externalLinks.json")); err == nil {
+		if err := json.Unmarshal(data, &state.ExternalLinks); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	deletions, err := readDeletions(path.Join(dataPath, "deletions.ndjson"))
+	if err != nil {
+		return err
+	}
+	state.Deletions = deletions
+
+	if p != "" {
+		return writeJSON(p, state)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
+
+// importState replaces the state of the workspace with the one exported to p. Downloaded files
+// are not touched, so files recorded in the state but missing locally are downloaded by the next sync.
+func importState(p string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d, expected %d", state.Version, stateVersion)
+	}
+
+	dataPath := path.Join(os.Getenv("WORKSPACE_DIR"), "knowledge", "integrations", "onedrive")
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return err
+	}
+	config := Config{}
+	if data, err := os.ReadFile(path.Join(dataPath, "config.json")); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if state.Files == nil {
+		state.Files = map[string]FileDetails{}
+	}
+	syncer := NewSyncer(nil, config, "", path.Join(dataPath, "metadata.json"), state.Files, NewSyncReport())
+	if err := syncer.flushMetadata(); err != nil {
+		return err
+	}
+	if err := writeDeletions(path.Join(dataPath, "deletions.ndjson"), state.Deletions); err != nil {
+		return err
+	}
+	if state.ExternalLinks == nil {
+		state.ExternalLinks = map[string]string{}
+	}
+	return writeJSON(path.Join(dataPath, "externalLinks.json"), state.ExternalLinks)
+}
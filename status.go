@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusListing  = "listing"
+	StatusSyncing  = "syncing"
+	StatusFinished = "finished"
+	StatusFailed   = "failed"
+)
+
+// SyncStatus is the progress of the current or last run, written to .sync-status.json in the
+// data directory. It is updated often and cheaply during the run, unlike metadata.json.
+type SyncStatus struct {
+	// Status is one of listing, syncing, finished or failed.
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	Files     int   `json:"files"`
+	FilesDone int   `json:"filesDone"`
+	Bytes     int64 `json:"bytes"`
+	BytesDone int64 `json:"bytesDone"`
+	// BytesPerSecond is the recent download throughput and RemainingSeconds the estimated time left, if known.
+	BytesPerSecond   float64 `json:"bytesPerSecond,omitempty"`
+	RemainingSeconds float64 `json:"remainingSeconds,omitempty"`
+}
+
+// writeStatus replaces the status file at p. Failing to write it does not affect the sync, so
+// errors are only logged.
+func writeStatus(p string, status SyncStatus) {
+	if p == "" {
+		return
+	}
+	status.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err == nil {
+		err = replaceFile(p, data)
+	}
+	if err != nil {
+		logrus.Debug("Could not write " + p + ": " + err.Error())
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Syncer downloads the files found in the sources and keeps their metadata up to date.
+type Syncer struct {
+	client    *msgraphsdk.GraphServiceClient
+	config    Config
+	outputDir string
+	// metadataPath is where metadata is saved, periodically during the run and at its end.
+	metadataPath string
+	metadata     map[string]FileDetails
+	report       *SyncReport
+	progress     *progress
+	// statusPath is where the progress of the run is written, if set.
+	statusPath string
+	// driveClients are the clients of drives reached through links with a profile of their own, by drive ID.
+	driveClients map[string]*msgraphsdk.GraphServiceClient
+
+	// lock guards the fields below and metadata while items are synced concurrently.
+	lock sync.Mutex
+	// deferred holds the items that failed with a transient error, to be retried at the end of the run.
+	deferred []models.DriveItemable
+	// written lists the files written during the run, when they are flushed at the end.
+	written []string
+	// unflushed counts the items updated since metadata was last written to metadataPath at flushed.
+	unflushed int
+	flushed   time.Time
+	// memory bounds the content buffered by downloads in progress, nil if unlimited.
+	memory *memoryBudget
+	// sample holds the IDs of the items to download when Config.Sample is set.
+	sample map[string]bool
+	// processed holds the IDs of the items synced, skipped or deferred so far.
+	processed map[string]bool
+	// deletionsPath is where the files deleted during the run are listed, if set.
+	deletionsPath string
+	deletions     []Deletion
+	// folderURLs caches the webUrl of the folders looked up during the run by drive and item ID.
+	folderURLs map[string]string
+	// incomplete holds the sources that could only be listed in part, whose files are not pruned.
+	incomplete map[string]bool
+	// cacheDir holds the files downloaded by the runs of a workspaces batch, if set, so a file
+	// shared by several workspaces is only downloaded once.
+	cacheDir string
+}
+
+func NewSyncer(client *msgraphsdk.GraphServiceClient, config Config, outputDir, metadataPath string, metadata map[string]FileDetails, report *SyncReport) *Syncer {
+	return &Syncer{
+		client:       client,
+		config:       config,
+		outputDir:    outputDir,
+		metadataPath: metadataPath,
+		metadata:     metadata,
+		report:       report,
+		flushed:      time.Now(),
+		memory:       newMemoryBudget(config.MaxMemoryMB << 20),
+		folderURLs:   map[string]string{},
+	}
+}
+
+// clientFor returns the client to access the drive of item with.
+func (s *Syncer) clientFor(item models.DriveItemable) *msgraphsdk.GraphServiceClient {
+	if parent := item.GetParentReference(); parent != nil && parent.GetDriveId() != nil {
+		if client, ok := s.driveClients[*parent.GetDriveId()]; ok {
+			return client
+		}
+	}
+	return s.client
+}
+
+func (s *Syncer) saveToMetadata(ctx context.Context, items map[string]models.DriveItemable, sources map[string][]string) error {
+	s.progress = newProgress(items, s.statusPath, s.report.StartedAt)
+	s.processed = map[string]bool{}
+	if s.config.Sample != nil {
+		s.sample = s.config.Sample.sample(items, func(item models.DriveItemable) bool {
+			detail, ok := s.metadata[*item.GetId()]
+			return (ok && detail.Sync) || (!ok && s.config.SyncNewFiles)
+		})
+	}
+
+	fatalErr := s.syncItems(ctx, items)
+	if fatalErr != nil && isSystemic(fatalErr) && s.config.RunRetryDelaySeconds > 0 {
+		remaining := map[string]models.DriveItemable{}
+		for id, item := range items {
+			if !s.processed[id] {
+				remaining[id] = item
+			}
+		}
+		delay := time.Duration(s.config.RunRetryDelaySeconds) * time.Second
+		logrus.Warn(fmt.Sprintf("Retrying the %d remaining files in %s after: %v", len(remaining), delay, fatalErr))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		fatalErr = s.syncItems(ctx, remaining)
+	}
+	if fatalErr != nil {
+		return fatalErr
+	}
+
+	failed := s.retryDeferred(ctx, s.deferred)
+	if err := s.syncWritten(); err != nil {
+		return err
+	}
+
+	for id, detail := range s.metadata {
+		if _, ok := items[id]; !ok {
+			if fromIncompleteSource(detail, s.incomplete) {
+				continue
+			}
+			removed, err := removeLocalCopy(s.outputDir, detail.localPath(id))
+			if err != nil {
+				return err
+			}
+			var derivatives []string
+			for _, derivative := range detail.Derivatives {
+				removedDerivative, err := removeLocalCopy(s.outputDir, derivative.FilePath)
+				if err != nil {
+					return err
+				}
+				if removedDerivative {
+					derivatives = append(derivatives, derivative.FilePath)
+				}
+			}
+			// Entries that were never downloaded, such as files not selected for sync, leave
+			// nothing behind to report.
+			if removed {
+				s.recordDeletion(Deletion{ID: id, Reason: DeletionRemoved, FilePath: detail.localPath(id), DisplayName: detail.DisplayName, URL: detail.URL, Derivatives: derivatives})
+				s.report.RemovedFiles = append(s.report.RemovedFiles, detail.DisplayName)
+			}
+			delete(s.metadata, id)
+			continue
+		}
+		detail.Sources = sources[id]
+		s.metadata[id] = detail
+	}
+	if err := s.writeDeletions(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %w", errPartialSync, errors.Join(failed...))
+	}
+	return nil
+}
+
+// syncItems syncs items, up to the configured concurrency at a time. The first error stops the
+// items not yet started and is returned.
+func (s *Syncer) syncItems(ctx context.Context, items map[string]models.DriveItemable) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		fatalErr error
+		workers  = make(chan struct{}, s.config.concurrency())
+	)
+	for _, item := range items {
+		workers <- struct{}{}
+		s.lock.Lock()
+		stop := fatalErr != nil
+		s.lock.Unlock()
+		if stop {
+			<-workers
+			break
+		}
+
+		wg.Add(1)
+		go func(item models.DriveItemable) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			err := s.syncItem(ctx, item)
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			if err == nil {
+				s.processed[*item.GetId()] = true
+			} else if fatalErr == nil {
+				fatalErr = err
+				cancel()
+			}
+		}(item)
+	}
+	wg.Wait()
+	return fatalErr
+}
+
+// syncItem records item in the metadata and downloads it if it is synced. Items that failed with
+// a transient error are deferred, any other error is returned.
+func (s *Syncer) syncItem(ctx context.Context, item models.DriveItemable) error {
+	s.lock.Lock()
+	detail, ok := s.metadata[*item.GetId()]
+	s.lock.Unlock()
+	if !ok && s.config.SyncNewFiles {
+		detail.Sync, ok = true, true
+	}
+
+	if ok && detail.Sync && s.sample != nil && !s.sample[*item.GetId()] {
+		s.report.skip(item, SkipNotSampled)
+	} else if ok && detail.Sync {
+		downloaded, err := s.downloadItem(ctx, item, detail)
+		if err != nil {
+			if s.skipFailed(item, err) {
+				return nil
+			}
+			if !isRetriable(err) {
+				return err
+			}
+			logrus.Warn(fmt.Sprintf("Deferring %s: %v", *item.GetName(), err))
+			s.lock.Lock()
+			s.deferred = append(s.deferred, item)
+			s.lock.Unlock()
+			return nil
+		}
+		detail = downloaded
+	}
+	return s.recordItem(ctx, item, detail)
+}
+
+// skipFailed reports whether the download of item failed in a way that only affects that file,
+// such as a protected file or a full disk, in which case it is recorded as skipped.
+func (s *Syncer) skipFailed(item models.DriveItemable, err error) bool {
+	if statusCode(err) == http.StatusForbidden {
+		logrus.Warn(fmt.Sprintf("Skipping protected file %s: %v", *item.GetName(), err))
+		s.report.skip(item, SkipProtected)
+		return true
+	}
+	if isOutOfSpace(err) {
+		logrus.Warn(fmt.Sprintf("Skipping %s, there is no space left for it: %v", *item.GetName(), err))
+		s.report.skip(item, SkipOverQuota)
+		return true
+	}
+	return false
+}
+
+// recordItem completes detail with the locations and properties of item and stores it in the
+// metadata, which is flushed periodically and when memory runs low.
+func (s *Syncer) recordItem(ctx context.Context, item models.DriveItemable, detail FileDetails) error {
+	detail.FolderURL = s.folderURL(ctx, item, detail)
+	detail.ItemURL = itemURL(s.clientFor(item), item)
+	detail.Properties = s.itemProperties(item, s.config.ItemProperties)
+	if s.config.IncludeListItemFields {
+		detail.ListItem = s.listItemDetails(ctx, item, detail)
+	} else {
+		detail.ListItem = nil
+	}
+	s.lock.Lock()
+	s.metadata[*item.GetId()] = updateDetail(detail, item, s.config.TimestampFormat)
+	s.unflushed++
+	flush := s.unflushed >= metadataFlushItems || time.Since(s.flushed) >= metadataFlushInterval
+	s.lock.Unlock()
+	s.progress.add(item)
+
+	if s.memory.underPressure() {
+		err := s.flushMetadata()
+		debug.FreeOSMemory()
+		return err
+	}
+	if flush {
+		return s.flushMetadata()
+	}
+	return nil
+}
+
+// isUpToDate reports whether the content recorded in detail is the current content of item. The
+// content hash is the most reliable way to tell whether the file changed, followed by the cTag,
+// which only changes with the content. The modification time is only used for files downloaded
+// before either was recorded.
+func isUpToDate(item models.DriveItemable, detail FileDetails) bool {
+	if hash := remoteHash(item); hash != "" && detail.QuickXorHash != "" {
+		return hash == detail.QuickXorHash
+	}
+	if item.GetCTag() != nil && detail.CTag != "" {
+		return *item.GetCTag() == detail.CTag
+	}
+	return sameTimestamp(detail.UpdatedAt, *item.GetLastModifiedDateTime())
+}
+
+// downloadItem downloads item unless the local copy is up to date and returns detail updated
+// with the local copy. A copy left under a previous name is removed.
+func (s *Syncer) downloadItem(ctx context.Context, item models.DriveItemable, detail FileDetails) (FileDetails, error) {
+	filePath := path.Join(s.config.routeDir(item), *item.GetId(), *item.GetName())
+	downloadPath := path.Join(s.outputDir, filePath)
+	upToDate := isUpToDate(item, detail)
+	if s.config.forcesResync(item) {
+		logrus.Info(fmt.Sprintf("Downloading %s again as requested", *item.GetName()))
+		upToDate = false
+	}
+	if _, err := os.Stat(downloadPath); err == nil && upToDate {
+		detail.FilePath = filePath
+		return detail, nil
+	}
+
+	// Chunked downloads hold one chunk at a time, other downloads the whole file.
+	chunked := s.config.ChunkedDownloadThreshold > 0 && itemSize(item) >= s.config.ChunkedDownloadThreshold
+	buffered := itemSize(item)
+	if chunked {
+		buffered = min(buffered, s.config.chunkSize())
+	}
+	release := s.memory.acquire(*item.GetName(), buffered)
+	defer release()
+
+	// A file is new unless an earlier copy is about to be replaced.
+	previous := detail.localPath(*item.GetId())
+	_, err := os.Stat(path.Join(s.outputDir, previous))
+	isNew := os.IsNotExist(err)
+
+	// Only create the directory once there is content to write, so failed items leave no empty directories behind.
+	var data []byte
+	cached, err := s.copyFromCache(item, downloadPath)
+	if err != nil {
+		return detail, err
+	}
+	if cached {
+		logrus.Info(fmt.Sprintf("Copied %s from the download cache", downloadPath))
+	} else {
+		if chunked {
+			if err := os.MkdirAll(path.Dir(downloadPath), 0755); err != nil {
+				return detail, err
+			}
+			err = s.downloadChunked(ctx, item, downloadPath)
+		} else if data, err = s.downloadContent(ctx, item); err == nil {
+			s.progress.transferred(int64(len(data)))
+			if err := os.MkdirAll(path.Dir(downloadPath), 0755); err != nil {
+				return detail, err
+			}
+			err = s.writeFile(downloadPath, data)
+		}
+		if err != nil {
+			return detail, err
+		}
+		logrus.Info(fmt.Sprintf("Downloaded %s", downloadPath))
+		s.addToCache(item, downloadPath)
+	}
+
+	s.report.downloaded(getDisplayName(item), isNew)
+
+	if previous != filePath {
+		removed, err := removeLocalCopy(s.outputDir, previous)
+		if err != nil {
+			return detail, err
+		}
+		if removed && !isNew {
+			s.recordDeletion(Deletion{ID: *item.GetId(), Reason: DeletionMoved, FilePath: previous, DisplayName: detail.DisplayName, URL: detail.URL})
+		}
+	}
+	detail.FilePath = filePath
+	detail.QuickXorHash = remoteHash(item)
+	detail.CTag = ""
+	if item.GetCTag() != nil {
+		detail.CTag = *item.GetCTag()
+	}
+
+	derivatives := s.writeDerivatives(ctx, item, filePath)
+	for _, old := range detail.Derivatives {
+		if !slices.ContainsFunc(derivatives, func(d Derivative) bool { return d.FilePath == old.FilePath }) {
+			if _, err := removeLocalCopy(s.outputDir, old.FilePath); err != nil {
+				return detail, err
+			}
+		}
+	}
+	detail.Derivatives = derivatives
+
+	if s.config.DetectLanguage && isText(item) {
+		if chunked || cached {
+			data, err = readSample(downloadPath)
+			if err != nil {
+				return detail, err
+			}
+		}
+		detail.Language = detectLanguage(string(data))
+	}
+	return detail, nil
+}
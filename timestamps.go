@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TimestampRFC3339 records times as RFC 3339 in UTC, e.g. 2024-05-01T09:30:00Z. This is the default.
+	TimestampRFC3339 = "rfc3339"
+	// TimestampEpochMillis records times as the number of milliseconds since the Unix epoch.
+	TimestampEpochMillis = "epochMillis"
+)
+
+// goTimeLayout is the layout of time.Time.String, which older versions recorded.
+const goTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// formatTimestamp renders t for metadata.json in the given format.
+func formatTimestamp(t time.Time, format string) string {
+	if format == TimestampEpochMillis {
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTimestamp parses a time recorded in any of the formats, including that of older versions.
+func parseTimestamp(s string) (time.Time, bool) {
+	if millis, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(millis), true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	// time.Time.String appends the monotonic clock reading, if any, after the zone.
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+	if t, err := time.Parse(goTimeLayout, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// sameTimestamp reports whether recorded is t, whatever format it was recorded in. Epoch millis
+// drop anything below a millisecond, so times are compared to the millisecond.
+func sameTimestamp(recorded string, t time.Time) bool {
+	parsed, ok := parseTimestamp(recorded)
+	return ok && parsed.UnixMilli() == t.UnixMilli()
+}
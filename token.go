@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+	"github.com/sirupsen/logrus"
+)
+
+// minTokenLifetime is the remaining token lifetime below which a warning is logged even without
+// knowing how long the sync will take.
+const minTokenLifetime = 5 * time.Minute
+
+// tokenClaims are the claims of a JWT access token used here.
+type tokenClaims struct {
+	Exp int64 `json:"exp"`
+	// Scp lists the delegated permissions separated by spaces, Roles the application permissions.
+	Scp   string   `json:"scp"`
+	Roles []string `json:"roles"`
+}
+
+// decodeToken returns the claims of a JWT access token. Tokens of personal Microsoft accounts are
+// opaque, in which case it returns false.
+func decodeToken(token string) (tokenClaims, bool) {
+	var claims tokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, false
+	}
+	return claims, json.Unmarshal(payload, &claims) == nil
+}
+
+// tokenExpiry returns the expiry of a JWT access token, if it can be decoded.
+func tokenExpiry(token string) (time.Time, bool) {
+	claims, ok := decodeToken(token)
+	if !ok || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// permissions returns the delegated and application permissions granted to the token.
+func (c tokenClaims) permissions() []string {
+	return append(strings.Fields(c.Scp), c.Roles...)
+}
+
+// checkTokenLifetime records when token expires and warns if that is likely to happen before a
+// sync taking as long as the previous one finishes.
+func checkTokenLifetime(report *SyncReport, token string, previousDuration time.Duration) {
+	expiresAt, ok := tokenExpiry(token)
+	if !ok {
+		return
+	}
+	report.TokenExpiresAt = &expiresAt
+
+	remaining := time.Until(expiresAt)
+	switch {
+	case remaining <= 0:
+		report.TokenWarning = fmt.Sprintf("token expired at %s", expiresAt.Format(time.RFC3339))
+	case remaining < previousDuration:
+		report.TokenWarning = fmt.Sprintf("token expires in %s but the previous sync took %s", remaining.Round(time.Second), previousDuration.Round(time.Second))
+	case remaining < minTokenLifetime:
+		report.TokenWarning = fmt.Sprintf("token expires in %s", remaining.Round(time.Second))
+	default:
+		return
+	}
+	report.warn(WarnTokenExpiring, "", report.TokenWarning)
+}
+
+// AuthError reports credentials that Graph does not accept.
+type AuthError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *AuthError) Error() string {
+	if e.Code == ErrTokenExpired {
+		return fmt.Sprintf("access token expired: %v", e.Err)
+	}
+	return fmt.Sprintf("authentication failed: %v", e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// checkAuthentication makes a cheap Graph call to fail early with an AuthError if the credentials
+// are rejected, rather than with whatever the first link happens to report. Other errors, including
+// app-only credentials having no /me, are left for the sync itself to run into.
+func checkAuthentication(ctx context.Context, client *msgraphsdk.GraphServiceClient, token string) error {
+	_, err := withRetry(ctx, graphRetry, func() (models.Userable, error) {
+		return client.Me().Get(ctx, &users.UserItemRequestBuilderGetRequestConfiguration{
+			QueryParameters: &users.UserItemRequestBuilderGetQueryParameters{
+				Select: []string{"id"},
+			},
+		})
+	})
+	if err == nil {
+		return nil
+	}
+
+	var credentialErr *azidentity.AuthenticationFailedError
+	if statusCode(err) != http.StatusUnauthorized && !errors.As(err, &credentialErr) {
+		logrus.Debug(fmt.Sprintf("Authentication check inconclusive: %v", err))
+		return nil
+	}
+	var odataErr *odataerrors.ODataError
+	if errors.As(err, &odataErr) && odataErr.GetErrorEscaped() != nil && odataErr.GetErrorEscaped().GetMessage() != nil {
+		err = errors.New(*odataErr.GetErrorEscaped().GetMessage())
+	}
+	if expiresAt, ok := tokenExpiry(token); ok && time.Now().After(expiresAt) {
+		return &AuthError{Code: ErrTokenExpired, Err: err}
+	}
+	return &AuthError{Code: ErrAuthenticationFailed, Err: err}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ToolResult is printed to stdout in tool mode as the response of the tool call.
+type ToolResult struct {
+	Files        int      `json:"files"`
+	NewFiles     []string `json:"newFiles"`
+	UpdatedFiles []string `json:"updatedFiles"`
+	RemovedFiles []string `json:"removedFiles"`
+	Skipped      int      `json:"skipped"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// isToolMode reports whether the binary was started as a GPTScript tool with `tool [arguments]`.
+func isToolMode() bool {
+	return len(os.Args) > 1 && os.Args[1] == "tool"
+}
+
+// ToolArguments are the settings a tool call may override. They use the keys of config.json but
+// are limited to what narrows the sync, so a tool call cannot change where files are written or
+// which commands are run.
+type ToolArguments struct {
+	Search            *string  `json:"search,omitempty"`
+	MaxDepth          *int     `json:"maxDepth,omitempty"`
+	Extensions        []string `json:"extensions,omitempty"`
+	ExcludeExtensions []string `json:"excludeExtensions,omitempty"`
+	IncludePatterns   []string `json:"includePatterns,omitempty"`
+	ExcludePatterns   []string `json:"excludePatterns,omitempty"`
+	SkipEmptyFiles    *bool    `json:"skipEmptyFiles,omitempty"`
+	MinFileSize       *int64   `json:"minFileSize,omitempty"`
+	MaxFileSize       *int64   `json:"maxFileSize,omitempty"`
+	ModifiedAfter     *string  `json:"modifiedAfter,omitempty"`
+	Authors           []string `json:"authors,omitempty"`
+	Resync            []string `json:"resync,omitempty"`
+}
+
+// toolArguments returns the JSON arguments of the tool call, given after the tool command or in
+// GPTSCRIPT_INPUT.
+func toolArguments() []byte {
+	input := os.Getenv("GPTSCRIPT_INPUT")
+	if len(os.Args) > 2 {
+		input = os.Args[2]
+	}
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	return []byte(input)
+}
+
+// applyToolArguments overrides the settings of config with the tool call arguments. Keys other
+// than those of ToolArguments are ignored.
+func applyToolArguments(config *Config) error {
+	data := toolArguments()
+	if data == nil {
+		return nil
+	}
+	var args ToolArguments
+	if err := json.Unmarshal(data, &args); err != nil {
+		return fmt.Errorf("invalid tool arguments: %w", err)
+	}
+
+	if args.Search != nil {
+		config.Search = *args.Search
+	}
+	if args.MaxDepth != nil {
+		config.MaxDepth = *args.MaxDepth
+	}
+	if args.Extensions != nil {
+		config.Extensions = args.Extensions
+	}
+	if args.ExcludeExtensions != nil {
+		config.ExcludeExtensions = args.ExcludeExtensions
+	}
+	if args.IncludePatterns != nil {
+		config.IncludePatterns = args.IncludePatterns
+	}
+	if args.ExcludePatterns != nil {
+		config.ExcludePatterns = args.ExcludePatterns
+	}
+	if args.SkipEmptyFiles != nil {
+		config.SkipEmptyFiles = *args.SkipEmptyFiles
+	}
+	if args.MinFileSize != nil {
+		config.MinFileSize = *args.MinFileSize
+	}
+	if args.MaxFileSize != nil {
+		config.MaxFileSize = *args.MaxFileSize
+	}
+	if args.ModifiedAfter != nil {
+		config.ModifiedAfter = *args.ModifiedAfter
+	}
+	if args.Authors != nil {
+		config.Authors = args.Authors
+	}
+	if args.Resync != nil {
+		config.Resync = args.Resync
+	}
+	return nil
+}
+
+func newToolResult(report *SyncReport, files int, syncErr error) ToolResult {
+	result := ToolResult{
+		Files:        files,
+		NewFiles:     report.NewFiles,
+		UpdatedFiles: report.UpdatedFiles,
+		RemovedFiles: report.RemovedFiles,
+		Skipped:      len(report.SkippedFiles),
+	}
+	if syncErr != nil {
+		result.Errors = strings.Split(syncErr.Error(), "\n")
+	}
+	return result
+}
+
+// exitWithError ends a run that failed before it could sync. In tool mode the error is also
+// printed as the tool result, since GPTScript only reads the response from stdout.
+func exitWithError(err error) {
+	if isToolMode() {
+		if err := printToolResult(ToolResult{Errors: strings.Split(err.Error(), "\n")}); err != nil {
+			logrus.Error(err)
+		}
+	}
+	logrus.Error(err)
+	os.Exit(1)
+}
+
+// printToolResult writes result to stdout, where GPTScript reads the tool response from.
+func printToolResult(result ToolResult) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// TransportConfig holds the network settings, read from the environment so that they apply
+// before anything else is loaded. Proxies are configured with the standard HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY variables.
+type TransportConfig struct {
+	// DialTimeout limits establishing a connection. ONEDRIVE_DIAL_TIMEOUT, default 30s.
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout limits waiting for a response once a request was sent, so a stalled
+	// server fails the request instead of hanging. ONEDRIVE_RESPONSE_HEADER_TIMEOUT, default 2m.
+	ResponseHeaderTimeout time.Duration
+	// RequestTimeout limits Graph API calls as a whole. Downloads are not limited, since large
+	// files take arbitrarily long. ONEDRIVE_REQUEST_TIMEOUT, default 100s.
+	RequestTimeout time.Duration
+	// MaxIdleConnsPerHost is the number of connections kept open for reuse per host.
+	// ONEDRIVE_MAX_IDLE_CONNS, default 32.
+	MaxIdleConnsPerHost int
+	// CAFile is a PEM file of additional trusted certificates, e.g. of a TLS-inspecting proxy.
+	// ONEDRIVE_CA_FILE.
+	CAFile string
+}
+
+func transportConfigFromEnv() (TransportConfig, error) {
+	config := TransportConfig{
+		DialTimeout:           30 * time.Second,
+		ResponseHeaderTimeout: 2 * time.Minute,
+		RequestTimeout:        100 * time.Second,
+		MaxIdleConnsPerHost:   32,
+		CAFile:                os.Getenv("ONEDRIVE_CA_FILE"),
+	}
+	for name, d := range map[string]*time.Duration{
+		"ONEDRIVE_DIAL_TIMEOUT":            &config.DialTimeout,
+		"ONEDRIVE_RESPONSE_HEADER_TIMEOUT": &config.ResponseHeaderTimeout,
+		"ONEDRIVE_REQUEST_TIMEOUT":         &config.RequestTimeout,
+	} {
+		if v := os.Getenv(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*d = parsed
+		}
+	}
+	if v := os.Getenv("ONEDRIVE_MAX_IDLE_CONNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return config, fmt.Errorf("invalid ONEDRIVE_MAX_IDLE_CONNS: %w", err)
+		}
+		config.MaxIdleConnsPerHost = n
+	}
+	return config, nil
+}
+
+// newTransport returns the transport shared by Graph API calls and downloads.
+func (c TransportConfig) newTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.DialContext = (&net.Dialer{
+		Timeout:   c.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return transport, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// webURLNoise are query parameters SharePoint and OneDrive add to webUrls for the session, the
+// client or tracking, which change without the item changing. Keys are compared in lower case.
+var webURLNoise = map[string]bool{
+	"action":          true,
+	"mobileredirect":  true,
+	"web":             true,
+	"e":               true,
+	"csf":             true,
+	"ct":              true,
+	"ovuser":          true,
+	"clickparams":     true,
+	"wdlor":           true,
+	"wdorigin":        true,
+	"wdexp":           true,
+	"wdenableroaming": true,
+}
+
+// normalizeWebURL returns u without the fragment and noise query parameters, with the remaining
+// parameters sorted and the host in lower case, so the URL recorded for an item stays the same
+// across runs. Parameters that identify the item, such as sourcedoc, are kept.
+func normalizeWebURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment, parsed.RawFragment = "", ""
+	query := parsed.Query()
+	for key := range query {
+		if webURLNoise[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// itemURL returns the Graph URL of item by drive and item ID, which keeps working when the item is
+// renamed or moved and SharePoint rewrites its webUrl.
+func itemURL(client *msgraphsdk.GraphServiceClient, item models.DriveItemable) string {
+	parent := item.GetParentReference()
+	if parent == nil || parent.GetDriveId() == nil {
+		return ""
+	}
+	return client.GetAdapter().GetBaseUrl() + "/drives/" + url.PathEscape(*parent.GetDriveId()) + "/items/" + url.PathEscape(*item.GetId())
+}
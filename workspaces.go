@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WorkspaceBatch lists the workspaces synced by the workspaces command.
+type WorkspaceBatch struct {
+	// Parallel is the number of workspaces synced at the same time. It defaults to one at a time.
+	Parallel int `json:"parallel,omitempty"`
+	// CacheDir keeps the files downloaded for any workspace, so files shared by several workspaces
+	// are downloaded once. It defaults to a temporary directory removed after the batch.
+	CacheDir   string           `json:"cacheDir,omitempty"`
+	Workspaces []WorkspaceEntry `json:"workspaces"`
+}
+
+type WorkspaceEntry struct {
+	WorkspaceDir string `json:"workspaceDir"`
+	// Links are added to the shared links of the workspace before it is synced.
+	Links []string `json:"links,omitempty"`
+}
+
+// syncWorkspaces syncs every workspace listed in the batch file at batchPath. Each workspace is
+// synced by a separate run of this binary with WORKSPACE_DIR set, so one failing workspace does
+// not affect the others. The runs share a download cache in ONEDRIVE_CACHE_DIR, and files already
+// downloaded for one workspace are copied from it rather than downloaded again.
+func syncWorkspaces(ctx context.Context, batchPath string) error {
+	data, err := os.ReadFile(batchPath)
+	if err != nil {
+		return err
+	}
+	var batch WorkspaceBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cacheDir := batch.CacheDir
+	if cacheDir == "" {
+		if cacheDir, err = os.MkdirTemp("", "onedrive-cache-*"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(cacheDir)
+	} else if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		failed  []error
+		workers = make(chan struct{}, max(batch.Parallel, 1))
+	)
+	for _, workspace := range batch.Workspaces {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(workspace WorkspaceEntry) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			if err := syncWorkspace(ctx, executable, cacheDir, workspace); err != nil {
+				logrus.Error(fmt.Sprintf("Syncing workspace %s failed: %v", workspace.WorkspaceDir, err))
+				lock.Lock()
+				failed = append(failed, fmt.Errorf("%s: %w", workspace.WorkspaceDir, err))
+				lock.Unlock()
+			}
+		}(workspace)
+	}
+	wg.Wait()
+	return errors.Join(failed...)
+}
+
+func syncWorkspace(ctx context.Context, executable, cacheDir string, workspace WorkspaceEntry) error {
+	if workspace.WorkspaceDir == "" {
+		return errors.New("workspaceDir is required")
+	}
+	if err := addExternalLinks(workspace.WorkspaceDir, workspace.Links); err != nil {
+		return err
+	}
+
+	logrus.Info(fmt.Sprintf("Syncing workspace %s", workspace.WorkspaceDir))
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Env = append(os.Environ(), "WORKSPACE_DIR="+workspace.WorkspaceDir, "ONEDRIVE_CACHE_DIR="+cacheDir)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// addExternalLinks adds links to externalLinks.json of the workspace, keeping the existing ones.
+func addExternalLinks(workspaceDir string, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	dataPath := path.Join(workspaceDir, "knowledge", "integrations", "onedrive")
+	externalLinkPath := path.Join(dataPath, "externalLinks.json")
+	externalLinks := map[string]string{}
+	if data, err := os.ReadFile(externalLinkPath); err == nil {
+		if err := json.Unmarshal(data, &externalLinks); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, link := range links {
+		if _, ok := externalLinks[link]; !ok {
+			externalLinks[link] = ""
+		}
+	}
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return err
+	}
+	return writeJSON(externalLinkPath, externalLinks)
+}
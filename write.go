@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FsyncNone leaves flushing written files to the operating system. This is the fastest.
+	FsyncNone = "none"
+	// FsyncFile flushes every file to disk before it is moved into place.
+	FsyncFile = "file"
+	// FsyncEnd flushes all written files once at the end of the run.
+	FsyncEnd = "end"
+)
+
+// OutputDirError reports a directory the sync cannot write files to.
+type OutputDirError struct {
+	Dir string
+	Err error
+}
+
+func (e *OutputDirError) Error() string {
+	return fmt.Sprintf("directory %s is not writable: %v", e.Dir, e.Err)
+}
+
+func (e *OutputDirError) Unwrap() error {
+	return e.Err
+}
+
+// checkWritable creates dir if needed and makes sure files can be created in it.
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &OutputDirError{Dir: dir, Err: err}
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return &OutputDirError{Dir: dir, Err: err}
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// writeFile writes data to a temporary file and moves it to dst once it is complete, so readers
+// never see a partially written file. Temporary files live in the configured temp directory, or
+// next to dst where the final rename is cheapest.
+func (s *Syncer) writeFile(dst string, data []byte) error {
+	dir := s.config.TempDir
+	if dir == "" {
+		dir = filepath.Dir(dst)
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(dst)+".partial-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.moveIntoPlace(tmp, dst)
+}
+
+// moveIntoPlace replaces dst with the complete file at tmp, flushing it as configured by fsync.
+func (s *Syncer) moveIntoPlace(tmp, dst string) error {
+	if s.config.Fsync == FsyncFile {
+		if err := syncFile(tmp); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+
+	err := os.Rename(tmp, dst)
+	if errors.Is(err, syscall.EXDEV) {
+		// The temp directory is on another filesystem, e.g. a scratch volume. The file is still
+		// renamed into place so that dst is replaced rather than overwritten, which keeps hard
+		// links to the previous content in snapshots intact.
+		local := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".copy")
+		if err = copyFile(tmp, local); err == nil {
+			err = os.Rename(local, dst)
+		}
+		if err != nil {
+			os.Remove(local)
+		} else {
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch s.config.Fsync {
+	case FsyncFile:
+		syncDir(filepath.Dir(dst))
+	case FsyncEnd:
+		s.lock.Lock()
+		s.written = append(s.written, dst)
+		s.lock.Unlock()
+	}
+	return nil
+}
+
+// syncWritten flushes the files written during the run when fsync is set to "end".
+func (s *Syncer) syncWritten() error {
+	dirs := map[string]bool{}
+	for _, p := range s.written {
+		if err := syncFile(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		dirs[filepath.Dir(p)] = true
+	}
+	for dir := range dirs {
+		syncDir(dir)
+	}
+	if len(s.written) > 0 {
+		logrus.Info(fmt.Sprintf("Flushed %d files to disk", len(s.written)))
+	}
+	s.written = nil
+	return nil
+}
+
+func syncFile(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// syncDir flushes a directory so that renames into it are durable. Not every platform supports
+// this, so failures are ignored.
+func syncDir(dir string) {
+	_ = syncFile(dir)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}